@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"jcli/jenkins"
+
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/term"
+)
+
+var (
+	artifactsGlob     string
+	artifactsDest     string
+	artifactsZip      bool
+	artifactsParallel int
+)
+
+// artifactsCmd represents the artifacts command
+var artifactsCmd = &cobra.Command{
+	Use:   "artifacts <job> [build|lastBuild]",
+	Short: "List and download the artifacts of a build",
+	Long: `Lists the artifacts archived by a build. The build defaults to lastBuild.
+
+With --dest the matching artifacts are downloaded into the given directory,
+keeping their relative paths. Interrupted downloads are resumed on the next
+run. With --zip all artifacts are downloaded as a single archive.zip, which
+cannot be combined with --glob.`,
+	Example: `  jcli artifacts my-job --glob '**/*.jar'
+  jcli artifacts my-job 42 --glob '**/*.jar' --dest out
+  jcli artifacts my-job --zip --dest out`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if artifactsParallel < 1 {
+			log.Fatal("Error: --parallel must be at least 1")
+		}
+		jobName := args[0]
+		ref := ""
+		if len(args) > 1 {
+			ref = args[1]
+		}
+		buildUrl, err := Jenkins.ResolveBuildUrl(jobName, ref)
+		if err != nil {
+			log.Fatal("Error: Could not find build ", Jenkins.BuildUrl(jobName, ref), ": ", err)
+		}
+
+		var downloads []*download
+		if artifactsZip {
+			if artifactsDest == "" {
+				artifactsDest = "."
+			}
+			downloads = append(downloads, &download{
+				name: "archive.zip",
+				url:  jenkins.ArchiveUrl(buildUrl),
+				dest: filepath.Join(artifactsDest, "archive.zip"),
+			})
+		} else {
+			artifacts, err := Jenkins.GetArtifacts(buildUrl)
+			if err != nil {
+				log.Fatal("Error: Could not list artifacts of build ", buildUrl, ": ", err)
+			}
+			for _, artifact := range artifacts {
+				if artifactsGlob != "" && !matchGlob(artifactsGlob, artifact.RelativePath) {
+					continue
+				}
+				if artifactsDest == "" {
+					fmt.Println(artifact.RelativePath)
+					continue
+				}
+				dest, err := artifactDest(artifactsDest, artifact.RelativePath)
+				if err != nil {
+					log.Fatal("Error: ", err)
+				}
+				downloads = append(downloads, &download{
+					name: artifact.RelativePath,
+					url:  jenkins.ArtifactUrl(buildUrl, artifact.RelativePath),
+					dest: dest,
+				})
+			}
+		}
+		if len(downloads) == 0 {
+			return
+		}
+
+		if !term.IsTerminal(int(os.Stdout.Fd())) {
+			if err := runDownloads(downloads, func(d *download) {
+				fmt.Println(d.dest)
+			}); err != nil {
+				log.Fatal("Error: ", err)
+			}
+			return
+		}
+		m := NewDownloadModel(downloads)
+		if _, err := tea.NewProgram(m).Run(); err != nil {
+			fmt.Println("could not start program:", err)
+		}
+		if m.err != nil {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(artifactsCmd)
+
+	artifactsCmd.Flags().StringVarP(&artifactsGlob, "glob", "g", "", "Only include artifacts matching the pattern, e.g. '**/*.jar'.")
+	artifactsCmd.Flags().StringVarP(&artifactsDest, "dest", "d", "", "Download the artifacts into this directory.")
+	artifactsCmd.Flags().BoolVar(&artifactsZip, "zip", false, "Download all artifacts as a single archive.zip.")
+	artifactsCmd.Flags().IntVar(&artifactsParallel, "parallel", 4, "Number of concurrent downloads.")
+	artifactsCmd.MarkFlagsMutuallyExclusive("zip", "glob")
+}
+
+// artifactDest returns the file an artifact is downloaded to. The relative
+// path comes from the server, so paths outside of dest are rejected.
+func artifactDest(dest, relativePath string) (string, error) {
+	rel := filepath.FromSlash(relativePath)
+	if filepath.IsAbs(rel) || strings.HasPrefix(relativePath, "/") || filepath.VolumeName(rel) != "" {
+		return "", fmt.Errorf("artifact path %s is absolute", relativePath)
+	}
+	file := filepath.Join(dest, rel)
+	within, err := filepath.Rel(dest, file)
+	if err != nil || within == "." || within == ".." || strings.HasPrefix(within, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("artifact path %s is outside of %s", relativePath, dest)
+	}
+	return file, nil
+}
+
+// matchGlob reports whether the slash separated name matches the pattern.
+// Besides the path.Match syntax, a "**" segment matches any number of
+// directories.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
+
+// download tracks the progress of a single file download
+type download struct {
+	name, url, dest string
+
+	mu      sync.Mutex
+	written int64
+	total   int64
+	done    bool
+	err     error
+}
+
+func (d *download) setProgress(written, total int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.written, d.total = written, total
+}
+
+func (d *download) finish(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.done, d.err = true, err
+}
+
+// percent returns the completed fraction of the download
+func (d *download) percent() float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case d.done && d.err == nil:
+		return 1
+	case d.total <= 0:
+		return 0
+	}
+	return float64(d.written) / float64(d.total)
+}
+
+// runDownloads downloads the files with at most --parallel downloads at a
+// time and calls onDone for every finished file
+func runDownloads(downloads []*download, onDone func(*download)) error {
+	var g errgroup.Group
+	g.SetLimit(artifactsParallel)
+	for _, d := range downloads {
+		d := d
+		g.Go(func() error {
+			err := Jenkins.DownloadFile(d.url, d.dest, d.setProgress)
+			d.finish(err)
+			if err != nil {
+				return fmt.Errorf("could not download %s: %w", d.name, err)
+			}
+			if onDone != nil {
+				onDone(d)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+type downloadTick struct{}
+type downloadsFinished struct{ err error }
+
+// DownloadModel shows a progress bar for each running download
+type DownloadModel struct {
+	downloads []*download
+	progress  progress.Model
+	err       error
+}
+
+func NewDownloadModel(downloads []*download) *DownloadModel {
+	return &DownloadModel{
+		downloads: downloads,
+		progress:  progress.New(progress.WithDefaultGradient(), progress.WithWidth(40)),
+	}
+}
+
+func tickDownloads() tea.Cmd {
+	return tea.Tick(100*time.Millisecond, func(time.Time) tea.Msg {
+		return downloadTick{}
+	})
+}
+
+func (m *DownloadModel) Init() tea.Cmd {
+	return tea.Batch(tickDownloads(), func() tea.Msg {
+		return downloadsFinished{runDownloads(m.downloads, nil)}
+	})
+}
+
+func (m *DownloadModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+	case downloadTick:
+		return m, tickDownloads()
+	case downloadsFinished:
+		m.err = msg.err
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m *DownloadModel) View() string {
+	var s strings.Builder
+	s.WriteString("\n")
+	for _, d := range m.downloads {
+		status := ""
+		d.mu.Lock()
+		switch {
+		case d.err != nil:
+			status = " " + d.err.Error()
+		case d.done:
+			status = " " + checkMark.Render()
+		}
+		d.mu.Unlock()
+		s.WriteString(mainStyle.Render(m.progress.ViewAs(d.percent()) + " " + d.name + status))
+		s.WriteString("\n")
+	}
+	if m.err != nil {
+		s.WriteString("\n" + mainStyle.Render("Error: "+m.err.Error()) + "\n")
+	}
+	return s.String()
+}
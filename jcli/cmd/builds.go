@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"jcli/jenkins"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+var (
+	buildsLimit int
+	buildsTui   bool
+)
+
+// buildsCmd represents the builds command
+var buildsCmd = &cobra.Command{
+	Use:   "builds <job>",
+	Short: "List the recent builds of a job",
+	Long: `Lists the most recent builds of a job with their result, duration,
+start time, cause and the commits they were built from.
+
+With --tui the builds are shown in an interactive table. Selecting a build
+opens its console log.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		jobName := args[0]
+		builds, err := Jenkins.GetBuilds(jobName, buildsLimit)
+		if err != nil {
+			log.Fatal("Error: Could not get builds of job ", jobName, ": ", err)
+		}
+		if !buildsTui {
+			printBuilds(builds)
+			return
+		}
+
+		f, err := tea.LogToFile("lazyjenkins.log", "builds")
+		if err != nil {
+			fmt.Println("fatal:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		p := tea.NewProgram(NewMainModel(NewBuildsModel(jobName, builds)), tea.WithAltScreen())
+		if _, err := p.Run(); err != nil {
+			fmt.Println("could not start program:", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(buildsCmd)
+
+	buildsCmd.Flags().IntVarP(&buildsLimit, "limit", "n", 10, "Number of builds to show.")
+	buildsCmd.Flags().BoolVarP(&buildsTui, "tui", "i", false, "Browse the builds interactively.")
+}
+
+// buildRow returns the columns shown for a build
+func buildRow(b jenkins.Build) []string {
+	commits := b.Commits()
+	for i, commit := range commits {
+		if len(commit) > 8 {
+			commits[i] = commit[:8]
+		}
+	}
+	return []string{
+		"#" + strconv.Itoa(b.Number),
+		b.Status(),
+		b.Elapsed().String(),
+		b.StartTime().Format("2006-01-02 15:04:05"),
+		strings.Join(b.Causes(), ", "),
+		strings.Join(commits, " "),
+	}
+}
+
+var buildColumns = []string{"BUILD", "RESULT", "DURATION", "STARTED", "CAUSE", "COMMITS"}
+
+// printBuilds prints the builds as a plain table to stdout
+func printBuilds(builds []jenkins.Build) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(buildColumns, "\t"))
+	for _, b := range builds {
+		fmt.Fprintln(w, strings.Join(buildRow(b), "\t"))
+	}
+	w.Flush()
+}
+
+var tableStyle = lipgloss.NewStyle().
+	BorderStyle(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("241"))
+
+// tableStyles returns the styles of the interactive tables
+func tableStyles() table.Styles {
+	styles := table.DefaultStyles()
+	styles.Header = styles.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color("241")).
+		BorderBottom(true).
+		Bold(true)
+	styles.Selected = styles.Selected.
+		Foreground(lipgloss.Color("229")).
+		Background(lipgloss.Color("57"))
+	return styles
+}
+
+// BuildsModel lists the builds of a job and opens the log of the selected one
+type BuildsModel struct {
+	JobName       string
+	builds        []jenkins.Build
+	table         table.Model
+	width, height int
+}
+
+func NewBuildsModel(jobName string, builds []jenkins.Build) *BuildsModel {
+	widths := []int{7, 9, 10, 20, 30, 20}
+	columns := make([]table.Column, len(buildColumns))
+	for i, title := range buildColumns {
+		columns[i] = table.Column{Title: title, Width: widths[i]}
+	}
+	rows := make([]table.Row, len(builds))
+	for i, b := range builds {
+		rows[i] = buildRow(b)
+	}
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithFocused(true),
+	)
+	t.SetStyles(tableStyles())
+
+	return &BuildsModel{
+		JobName: jobName,
+		builds:  builds,
+		table:   t,
+	}
+}
+
+func (m *BuildsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *BuildsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.table.SetHeight(m.height - 8)
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc", "q":
+			return m, popScreen
+		case "o": // Open the build in the browser
+			if len(m.builds) > 0 {
+				jenkins.Openbrowser(m.builds[m.table.Cursor()].Url)
+			}
+		case "enter":
+			if len(m.builds) == 0 {
+				return m, nil
+			}
+			build := m.builds[m.table.Cursor()]
+			log.Println("Info: Opening log of build", build.Url)
+			return m, pushScreen(NewBuildModelFromUrl(m.JobName, build.Url, m.width, m.height))
+		}
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m *BuildsModel) Title() string {
+	return "Builds of " + m.JobName
+}
+
+func (m *BuildsModel) Help() []keyHelp {
+	return []keyHelp{
+		{"enter", "show the log of the build"},
+		{"o", "open the build in the browser"},
+		{"j/k", "move down/up"},
+	}
+}
+
+func (m *BuildsModel) View() string {
+	title := keywordStyle.Render(m.JobName) + subtleStyle.Render(fmt.Sprintf(" • %d builds", len(m.builds)))
+	help := helpStyle.Render("\n enter: show log • o: open in browser • j/↓: down • k/↑: up • q: exit\n")
+	return mainStyle.Render("\n"+title+"\n\n"+tableStyle.Render(m.table.View())) + help
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"jcli/jenkins"
+
+	"github.com/spf13/cobra"
+)
+
+// convertCmd represents the convert command
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert declarative pipelines to JSON and back",
+	Long: `Convert declarative pipelines between Jenkinsfiles and their JSON
+representation with the Pipeline: Declarative plugin of the server. The JSON
+can be generated or checked by other tools, and converted back into a
+Jenkinsfile. A file named - is read from standard input.
+
+Comments are lost in the conversion, and scripted pipelines cannot be
+converted.`,
+}
+
+var convertToJSONCmd = &cobra.Command{
+	Use:   "to-json [Jenkinsfile]",
+	Short: "Print the JSON representation of a declarative pipeline",
+	Long: `Print the JSON representation of a declarative pipeline. The file defaults
+to Jenkinsfile. With --summary, only the agents and stages of the pipeline are
+listed.`,
+	Example: `  jcli convert to-json Jenkinsfile | jq '.pipeline.stages[].name'
+  jcli convert to-json --summary`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		script, err := readConvertInput(args, "Jenkinsfile")
+		if err != nil {
+			log.Fatal("Error: ", err)
+		}
+		data, err := Jenkins.PipelineToJSON(script)
+		if err != nil {
+			fatalConvert(err)
+		}
+		if convertSummary {
+			summary, err := jenkins.SummarizePipeline(data)
+			if err != nil {
+				log.Fatal("Error: ", err)
+			}
+			printPipelineSummary(os.Stdout, summary)
+			return
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, data, "", "  "); err != nil {
+			log.Fatal("Error: ", err)
+		}
+		fmt.Println(out.String())
+	},
+}
+
+var convertToJenkinsfileCmd = &cobra.Command{
+	Use:   "to-jenkinsfile <json file>",
+	Short: "Print the Jenkinsfile of the JSON representation of a pipeline",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := readConvertInput(args, "")
+		if err != nil {
+			log.Fatal("Error: ", err)
+		}
+		script, err := Jenkins.PipelineFromJSON([]byte(data))
+		if err != nil {
+			fatalConvert(err)
+		}
+		fmt.Println(strings.TrimSuffix(script, "\n"))
+	},
+}
+
+var convertNormalizeCmd = &cobra.Command{
+	Use:   "normalize [Jenkinsfile]",
+	Short: "Format a declarative pipeline the way the server writes it",
+	Long: `Format a declarative pipeline by converting it to JSON and back. The file
+defaults to Jenkinsfile. The result is printed, or with --write saved to the
+file. Comments are lost.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		script, err := readConvertInput(args, "Jenkinsfile")
+		if err != nil {
+			log.Fatal("Error: ", err)
+		}
+		data, err := Jenkins.PipelineToJSON(script)
+		if err != nil {
+			fatalConvert(err)
+		}
+		normalized, err := Jenkins.PipelineFromJSON(data)
+		if err != nil {
+			fatalConvert(err)
+		}
+		normalized = strings.TrimSuffix(normalized, "\n") + "\n"
+		if !convertWrite {
+			fmt.Print(normalized)
+			return
+		}
+		file := "Jenkinsfile"
+		if len(args) > 0 {
+			file = args[0]
+		}
+		if file == "-" {
+			log.Fatal("Error: --write needs a file")
+		}
+		if normalized == script {
+			fmt.Println(file, "is normalized already")
+			return
+		}
+		if err := os.WriteFile(filepath.Clean(file), []byte(normalized), 0o644); err != nil {
+			log.Fatal("Error: ", err)
+		}
+		fmt.Println("Normalized", file)
+	},
+}
+
+var (
+	convertSummary bool
+	convertWrite   bool
+)
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.AddCommand(convertToJSONCmd, convertToJenkinsfileCmd, convertNormalizeCmd)
+	convertToJSONCmd.Flags().BoolVarP(&convertSummary, "summary", "s", false, "Only list the agents and stages.")
+	convertNormalizeCmd.Flags().BoolVarP(&convertWrite, "write", "w", false, "Save the result to the file instead of printing it.")
+}
+
+// readConvertInput reads the file of the arguments, or the default file if
+// none is given. The file - is standard input.
+func readConvertInput(args []string, defaultFile string) (string, error) {
+	file := defaultFile
+	if len(args) > 0 {
+		file = args[0]
+	}
+	if file == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return string(data), err
+	}
+	return jenkins.LoadPipelineScriptFromFile(filepath.Clean(file))
+}
+
+// fatalConvert exits with the reason a conversion failed
+func fatalConvert(err error) {
+	var conversion *jenkins.ConversionError
+	switch {
+	case errors.As(err, &conversion):
+		for _, msg := range conversion.Messages {
+			fmt.Fprintln(os.Stderr, msg)
+		}
+		log.Fatal("Error: The server could not convert the pipeline")
+	case errors.Is(err, jenkins.ErrLinterUnavailable):
+		log.Fatal("Error: Converting needs the Pipeline: Declarative plugin on the server")
+	}
+	log.Fatal("Error: ", err)
+}
+
+// printPipelineSummary lists the agent of the pipeline and its stages with
+// their agents, nested stages indented
+func printPipelineSummary(w io.Writer, summary *jenkins.PipelineSummary) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "STAGE\tAGENT\n")
+	fmt.Fprintf(tw, "(pipeline)\t%s\n", summary.Agent)
+	var printStages func(stages []jenkins.PipelineStage, indent, kind string)
+	printStages = func(stages []jenkins.PipelineStage, indent, kind string) {
+		for _, stage := range stages {
+			fmt.Fprintf(tw, "%s%s%s\t%s\n", indent, stage.Name, kind, stage.Agent)
+			printStages(stage.Parallel, indent+"  ", " (parallel)")
+			printStages(stage.Stages, indent+"  ", "")
+		}
+	}
+	printStages(summary.Stages, "", "")
+	tw.Flush()
+}
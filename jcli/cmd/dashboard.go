@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"jcli/jenkins"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/sync/errgroup"
+)
+
+// jobStatus is the state of a job shown on the dashboard
+type jobStatus struct {
+	name string
+	job  *jenkins.Job
+	// queuePos is the position of the job in the build queue, starting at 1,
+	// or 0 if it is not queued
+	queuePos   int
+	queueSince time.Duration
+	err        error
+}
+
+// dashboardData, dashboardTick and dashboardMessage are tagged with the
+// dashboard they belong to, messages are broadcast to all screens
+type dashboardData struct {
+	model    *DashboardModel
+	statuses []jobStatus
+	err      error
+}
+type dashboardTick struct{ model *DashboardModel }
+type dashboardMessage struct {
+	model *DashboardModel
+	text  string
+}
+
+// DashboardModel polls a set of jobs and shows their state in a table
+type DashboardModel struct {
+	jobs          []string
+	folders       []string
+	interval      time.Duration
+	statuses      []jobStatus
+	table         table.Model
+	message       string
+	width, height int
+	// abortUrl is the build to abort once it is confirmed
+	abortUrl string
+}
+
+var dashboardColumns = []table.Column{
+	{Title: "JOB", Width: 30},
+	{Title: "STATUS", Width: 14},
+	{Title: "PROGRESS", Width: 18},
+	{Title: "QUEUE", Width: 14},
+	{Title: "LAST RESULT", Width: 16},
+}
+
+func NewDashboardModel(width, height int) *DashboardModel {
+	t := table.New(
+		table.WithColumns(dashboardColumns),
+		table.WithFocused(true),
+		table.WithHeight(max(height-9, 3)),
+	)
+	t.SetStyles(tableStyles())
+
+	return &DashboardModel{
+		jobs:     Config.Dashboard.Jobs,
+		folders:  Config.Dashboard.Folders,
+		interval: Config.Dashboard.Interval,
+		table:    t,
+		message:  "Loading jobs...",
+		width:    width,
+		height:   height,
+	}
+}
+
+func (m *DashboardModel) Init() tea.Cmd {
+	return tea.Batch(m.poll(), m.tick())
+}
+
+// tick schedules the next refresh
+func (m *DashboardModel) tick() tea.Cmd {
+	return tea.Tick(m.interval, func(time.Time) tea.Msg { return dashboardTick{model: m} })
+}
+
+// poll fetches the state of all watched jobs and the build queue
+func (m *DashboardModel) poll() tea.Cmd {
+	return func() tea.Msg {
+		names := append([]string{}, m.jobs...)
+		for _, folder := range m.folders {
+			jobs, err := Jenkins.ListJobs(folder)
+			if err != nil {
+				return dashboardData{model: m, err: fmt.Errorf("could not list folder %s: %w", folder, err)}
+			}
+			for _, job := range jobs {
+				names = append(names, job.FullName)
+			}
+		}
+		queue, err := Jenkins.GetQueue()
+		if err != nil {
+			return dashboardData{model: m, err: fmt.Errorf("could not get build queue: %w", err)}
+		}
+
+		statuses := make([]jobStatus, len(names))
+		var g errgroup.Group
+		g.SetLimit(8)
+		for i, name := range names {
+			i, name := i, name
+			g.Go(func() error {
+				job, err := Jenkins.GetJob(name, 1)
+				status := jobStatus{name: name, job: job, err: err}
+				for pos, item := range queue {
+					if job != nil && item.Task.Url == job.Url {
+						status.queuePos = pos + 1
+						status.queueSince = item.Waiting()
+						break
+					}
+				}
+				statuses[i] = status
+				return nil
+			})
+		}
+		g.Wait()
+		return dashboardData{model: m, statuses: statuses}
+	}
+}
+
+// progressBar renders the fraction done as a bar of the given width
+func progressBar(fraction float64, width int) string {
+	filled := int(fraction * float64(width))
+	filled = min(max(filled, 0), width)
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled) +
+		fmt.Sprintf(" %3d%%", int(fraction*100))
+}
+
+// dashboardRow returns the columns shown for a job
+func dashboardRow(s jobStatus) table.Row {
+	if s.err != nil {
+		return table.Row{s.name, "ERROR", "", "", s.err.Error()}
+	}
+	status, progress, queue, last := "IDLE", "", "", ""
+	if strings.HasSuffix(s.job.Color, "disabled") {
+		status = "DISABLED"
+	}
+	if b := s.job.LastBuild; b != nil && b.Building {
+		status = "RUNNING #" + strconv.Itoa(b.Number)
+		if b.EstimatedDuration > 0 {
+			elapsed := time.Since(b.StartTime())
+			progress = progressBar(float64(elapsed.Milliseconds())/float64(b.EstimatedDuration), 10)
+		}
+	}
+	if s.queuePos > 0 {
+		if status == "IDLE" {
+			status = "QUEUED"
+		}
+		queue = fmt.Sprintf("#%d · %s", s.queuePos, s.queueSince)
+	}
+	if b := s.job.LastCompletedBuild; b != nil {
+		last = fmt.Sprintf("%s #%d", b.Result, b.Number)
+	}
+	return table.Row{s.name, status, progress, queue, last}
+}
+
+// selected returns the job under the cursor
+func (m *DashboardModel) selected() *jobStatus {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.statuses) || m.statuses[cursor].job == nil {
+		return nil
+	}
+	return &m.statuses[cursor]
+}
+
+func (m *DashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.table.SetHeight(max(m.height-9, 3))
+	case dashboardData:
+		if msg.model != m {
+			return m, nil
+		}
+		if msg.err != nil {
+			log.Println("Error:", msg.err)
+			m.message = "⚠️ " + msg.err.Error()
+		} else {
+			m.statuses = msg.statuses
+			rows := make([]table.Row, len(m.statuses))
+			for i, s := range m.statuses {
+				rows[i] = dashboardRow(s)
+			}
+			m.table.SetRows(rows)
+			if len(rows) == 0 {
+				m.message = "No jobs configured. Add them to the dashboard section of " + cfgFile
+			} else if strings.HasPrefix(m.message, "Loading") {
+				m.message = ""
+			}
+		}
+		return m, nil
+	case dashboardTick:
+		if msg.model != m {
+			return m, nil
+		}
+		return m, tea.Batch(m.poll(), m.tick())
+	case dashboardMessage:
+		if msg.model != m {
+			return m, nil
+		}
+		m.message = msg.text
+		return m, nil
+	case tea.KeyMsg:
+		if m.abortUrl != "" {
+			// Only y aborts the build, any other key cancels
+			buildUrl := m.abortUrl
+			m.abortUrl = ""
+			if msg.String() == "y" {
+				return m, m.abortBuild(buildUrl)
+			}
+			m.message = "The build was not aborted"
+			return m, nil
+		}
+		switch msg.String() {
+		case "esc", "q":
+			return m, popScreen
+		case "r": // Refresh now
+			return m, m.poll()
+		case "b": // Trigger a build
+			if s := m.selected(); s != nil {
+				return m, m.triggerBuild(s.name)
+			}
+		case "x": // Abort the running build
+			if s := m.selected(); s != nil && s.job.LastBuild != nil && s.job.LastBuild.Building {
+				m.abortUrl = s.job.LastBuild.Url
+				m.message = fmt.Sprintf("Abort %s #%d? (y/n)", s.name, s.job.LastBuild.Number)
+				return m, nil
+			}
+		case "o": // Open the job in the browser
+			if s := m.selected(); s != nil {
+				jenkins.Openbrowser(s.job.Url)
+			}
+		case "n": // Show the nodes, to find out why builds wait
+			return m, pushScreen(NewNodesModel(m.width, m.height))
+		case "enter", "l": // Show the log of the last build
+			s := m.selected()
+			if s == nil {
+				return m, nil
+			}
+			if s.job.LastBuild == nil {
+				m.message = s.name + " has no builds yet"
+				return m, nil
+			}
+			return m, pushScreen(NewBuildModelFromUrl(s.name, s.job.LastBuild.Url, m.width, m.height))
+		}
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+// triggerBuild queues a build of the job and refreshes the dashboard
+func (m *DashboardModel) triggerBuild(jobName string) tea.Cmd {
+	return tea.Sequence(func() tea.Msg {
+		if _, err := Jenkins.QueueBuild(jobName); err != nil {
+			log.Println("Error:", err)
+			return dashboardMessage{model: m, text: "⚠️ Could not trigger " + jobName + ": " + err.Error()}
+		}
+		return dashboardMessage{model: m, text: "🚀 Triggered " + jobName}
+	}, m.poll())
+}
+
+// abortBuild stops the build and refreshes the dashboard
+func (m *DashboardModel) abortBuild(buildUrl string) tea.Cmd {
+	return tea.Sequence(func() tea.Msg {
+		if err := Jenkins.AbortBuild(buildUrl); err != nil {
+			log.Println("Error:", err)
+			return dashboardMessage{model: m, text: "⚠️ Could not abort " + buildUrl + ": " + err.Error()}
+		}
+		return dashboardMessage{model: m, text: "🛑 Aborted " + buildUrl}
+	}, m.poll())
+}
+
+func (m *DashboardModel) Title() string {
+	return "Dashboard"
+}
+
+func (m *DashboardModel) Help() []keyHelp {
+	return []keyHelp{
+		{"enter/l", "show the log of the last build"},
+		{"b", "trigger a build"},
+		{"x", "abort the running build"},
+		{"o", "open the job in the browser"},
+		{"n", "show the nodes"},
+		{"r", "refresh now"},
+	}
+}
+
+func (m *DashboardModel) View() string {
+	title := keywordStyle.Render("Dashboard") +
+		subtleStyle.Render(fmt.Sprintf(" • %d jobs • every %s", len(m.statuses), m.interval))
+	help := helpStyle.Render("\n enter: logs • b: build • x: abort • o: open in browser • n: nodes • r: refresh • q: exit\n")
+	return mainStyle.Render("\n"+title+"\n\n"+tableStyle.Render(m.table.View())+"\n"+m.message) + help
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"jcli/diff"
+	"jcli/jenkins"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	diffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	diffHunkStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+	diffHeaderStyle  = lipgloss.NewStyle().Bold(true)
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff [file]",
+	Short: "Show how a local pipeline script differs from the job on the server",
+	Long: `Show the changes update would make to the pipeline script of the job as a
+unified diff. The file defaults to Jenkinsfile and the job is looked up in the
+job mappings, unless it is given with --job.
+
+With --whole-config, the file is a whole config.xml, which is compared with
+the config of the job. Both are formatted the same way first, so that only
+meaningful differences are shown.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file := "Jenkinsfile"
+		if len(args) > 0 {
+			file = args[0]
+		}
+		jobName := diffJob
+		if jobName == "" {
+			var ok bool
+			if jobName, ok = mappedJob(file); !ok {
+				log.Fatal("Error: ", file, " is not mapped to a job, give it with --job")
+			}
+		}
+		changes, err := jobDiff(file, jobName, diffConfig)
+		if err != nil {
+			log.Fatal("Error: Could not compare ", file, " with ", jobName, ": ", err)
+		}
+		if changes == "" {
+			fmt.Println("No changes")
+			return
+		}
+		fmt.Print(colorDiff(changes))
+	},
+}
+
+var (
+	diffJob    string
+	diffConfig bool
+)
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVarP(&diffJob, "job", "j", "", "Job to compare with instead of the one the file is mapped to.")
+	diffCmd.Flags().BoolVar(&diffConfig, "whole-config", false, "Compare a whole config.xml instead of the pipeline script.")
+}
+
+// jobDiff returns the unified diff from the job on the server to the local
+// file, comparing the pipeline scripts or with wholeConfig the config.xml.
+// Jobs which do not exist yet are compared as empty.
+func jobDiff(file, jobName string, wholeConfig bool) (string, error) {
+	local, err := jenkins.LoadPipelineScriptFromFile(filepath.Clean(file))
+	if err != nil {
+		return "", err
+	}
+	exists, err := Jenkins.JobExists(jobName)
+	if err != nil {
+		return "", err
+	}
+	current := ""
+	if exists {
+		config, err := Jenkins.GetJobConfig(jobName)
+		if err != nil {
+			return "", err
+		}
+		if wholeConfig {
+			current, err = jenkins.NormalizeConfig(config)
+		} else {
+			current, err = jenkins.ExtractPipelineScript(config)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if wholeConfig {
+		if local, err = jenkins.NormalizeConfig(local); err != nil {
+			return "", fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return diff.Unified(jobName+" (server)", file, current, local, 3), nil
+}
+
+// colorDiff colors the added, removed and hunk lines of a unified diff
+func colorDiff(changes string) string {
+	lines := strings.Split(strings.TrimSuffix(changes, "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			lines[i] = diffHeaderStyle.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = diffHunkStyle.Render(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = diffAddedStyle.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = diffRemovedStyle.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// confirmUpdate returns the screen which shows the changes to the job of
+// the build before updating it, or the build itself with --yes
+func confirmUpdate(build *BuildModel) tea.Model {
+	if updateYes {
+		return build
+	}
+	return NewDiffModel(build)
+}
+
+type diffResult struct {
+	model   *DiffModel
+	changes string
+	err     error
+}
+
+// DiffModel shows the changes to the pipeline script of a job and starts
+// the build once they are confirmed
+type DiffModel struct {
+	build    *BuildModel
+	viewport viewport.Model
+	// loaded is set once the changes are shown, only then the update can
+	// be confirmed
+	loaded  bool
+	message string
+}
+
+func NewDiffModel(build *BuildModel) *DiffModel {
+	vp := viewport.New(build.width-3, build.height-8)
+	vp.Style = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		Margin(1, 1, 0).
+		BorderForeground(lipgloss.Color("241"))
+	vp.SetContent("Comparing " + build.File + " with " + build.JobName + "...")
+	return &DiffModel{build: build, viewport: vp}
+}
+
+func (m *DiffModel) Init() tea.Cmd {
+	return func() tea.Msg {
+		changes, err := jobDiff(m.build.File, m.build.JobName, false)
+		return diffResult{model: m, changes: changes, err: err}
+	}
+}
+
+func (m *DiffModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.viewport.Width = msg.Width - 3
+		m.viewport.Height = msg.Height - 8
+	case diffResult:
+		if msg.model != m {
+			return m, nil
+		}
+		switch {
+		case msg.err != nil:
+			log.Println("Error:", msg.err)
+			m.message = "⚠️ Could not compare with the job: " + msg.err.Error()
+			m.viewport.SetContent("")
+		case msg.changes == "":
+			// Nothing to confirm, build right away
+			return m, replaceScreen(m.build)
+		default:
+			m.loaded = true
+			m.viewport.SetContent(colorDiff(msg.changes))
+		}
+		return m, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "y", "enter":
+			if m.loaded {
+				return m, replaceScreen(m.build)
+			}
+			return m, nil
+		case "n", "q", "esc":
+			return m, m.build.close()
+		}
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m *DiffModel) Title() string {
+	return "Changes " + m.build.JobName
+}
+
+func (m *DiffModel) Help() []keyHelp {
+	return []keyHelp{
+		{"y/enter", "update the job and build it"},
+		{"n/q/esc", "cancel"},
+		{"j/k", "scroll down/up"},
+	}
+}
+
+func (m *DiffModel) View() string {
+	title := keywordStyle.Render("Update "+m.build.JobName) + subtleStyle.Render(" with "+m.build.File)
+	if m.message != "" {
+		title += "\n" + m.message
+	}
+	help := helpStyle.Render("\n y/enter: update and build • n/esc: cancel • j/k: scroll\n")
+	return mainStyle.Render("\n"+title) + "\n" + m.viewport.View() + help
+}
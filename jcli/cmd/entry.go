@@ -5,7 +5,10 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -24,7 +27,14 @@ var entryCmd = &cobra.Command{
 			os.Exit(1)
 		}
 		defer f.Close()
-		p := tea.NewProgram(NewMainModel(), tea.WithAltScreen())
+		var root tea.Model
+		if entryDashboard {
+			root = NewDashboardModel(80, 24)
+		} else {
+			pickModel := NewPickModel()
+			root = &pickModel
+		}
+		p := tea.NewProgram(NewMainModel(root), tea.WithAltScreen())
 		if _, err := p.Run(); err != nil {
 			fmt.Println("could not start program:", err)
 		}
@@ -32,11 +42,11 @@ var entryCmd = &cobra.Command{
 	},
 }
 
+var entryDashboard bool
+
 func init() {
 	rootCmd.AddCommand(entryCmd)
-	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
-		InitJenkins()
-	}
+	entryCmd.Flags().BoolVarP(&entryDashboard, "dashboard", "d", false, "Start with the dashboard of the configured jobs.")
 }
 
 // General stuff for styling the view
@@ -48,47 +58,242 @@ var (
 	mainStyle     = lipgloss.NewStyle().MarginLeft(2)
 )
 
-type sessionState int
+// pushScreenMsg opens a new screen on top of the current one
+type pushScreenMsg struct {
+	model tea.Model
+}
+
+// replaceScreenMsg replaces the current screen with a new one
+type replaceScreenMsg struct {
+	model tea.Model
+}
+
+// popScreenMsg closes the current screen and returns to the previous one
+type popScreenMsg struct{}
+
+// pushScreen opens the screen on top of the current one
+func pushScreen(model tea.Model) tea.Cmd {
+	return func() tea.Msg {
+		return pushScreenMsg{model: model}
+	}
+}
+
+// replaceScreen opens the screen in place of the current one, so that going
+// back skips the current screen
+func replaceScreen(model tea.Model) tea.Cmd {
+	return func() tea.Msg {
+		return replaceScreenMsg{model: model}
+	}
+}
+
+// popScreen returns to the previous screen, or quits on the first screen
+func popScreen() tea.Msg {
+	return popScreenMsg{}
+}
+
+// titled is implemented by screens which name themselves in the breadcrumb
+type titled interface {
+	Title() string
+}
+
+// keyHelp describes a key binding in the help overlay
+type keyHelp struct {
+	key, desc string
+}
+
+// helper is implemented by screens which list their keys in the help overlay
+type helper interface {
+	Help() []keyHelp
+}
+
+// inputCapturer is implemented by screens which currently read text input,
+// so that global keys like ? are passed on to them
+type inputCapturer interface {
+	CapturesInput() bool
+}
 
-const (
-	filepickView sessionState = iota
-	buildView
+var (
+	breadcrumbStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241")).MarginLeft(2)
+	overlayStyle    = lipgloss.NewStyle().
+			BorderStyle(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("141")).
+			Padding(1, 2)
 )
 
-type MainModel struct {
-	rootModel  tea.Model
-	ActiveFile string
+// reconnecting is sent to all screens whenever a request to Jenkins failed
+// and is retried
+type reconnecting struct {
+	reason string
+	wait   time.Duration
+}
 
-	Loaded   bool
-	Quitting bool
+func (r reconnecting) String() string {
+	return fmt.Sprintf("🔌 Reconnecting to Jenkins… %s, retrying in %s", r.reason, r.wait.Round(100*time.Millisecond))
 }
 
-func NewMainModel() MainModel {
-	var newrootModel tea.Model
+// retries passes the retries of the client to the running program
+var retries = make(chan reconnecting, 1)
 
-	pickModel := NewPickModel()
-	newrootModel = &pickModel
-	return MainModel{
-		rootModel: newrootModel,
+// notifyRetry is called by the client before it retries a request. Notices
+// are dropped while the previous one was not handled yet.
+func notifyRetry(req *http.Request, resp *http.Response, err error, wait time.Duration) {
+	notice := reconnecting{wait: wait}
+	if err != nil {
+		notice.reason = err.Error()
+	} else {
+		notice.reason = resp.Status
+	}
+	select {
+	case retries <- notice:
+	default:
 	}
+}
+
+// waitForRetry waits for the next retry of the client
+func waitForRetry() tea.Msg {
+	return <-retries
+}
 
+// globalHelp lists the keys handled by the MainModel on every screen
+var globalHelp = []keyHelp{
+	{"?", "toggle this help"},
+	{"esc/q", "back to the previous screen"},
+	{"ctrl+c", "quit"},
+}
+
+// MainModel routes messages to a stack of screens. New screens are pushed
+// on top with pushScreen and closed again with popScreen.
+type MainModel struct {
+	stack    []tea.Model
+	width    int
+	height   int
+	showHelp bool
+}
+
+func NewMainModel(root tea.Model) MainModel {
+	return MainModel{
+		stack: []tea.Model{root},
+	}
 }
 
 func (m MainModel) Init() tea.Cmd {
-	return m.rootModel.Init()
+	return tea.Batch(m.top().Init(), waitForRetry)
+}
+
+// top returns the active screen
+func (m MainModel) top() tea.Model {
+	return m.stack[len(m.stack)-1]
+}
+
+// screenSize returns the size available to screens below the breadcrumb
+func (m MainModel) screenSize() tea.WindowSizeMsg {
+	return tea.WindowSizeMsg{Width: m.width, Height: m.height - 1}
 }
 
 // Main update function. Handle state
 func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	return m.rootModel.Update(msg)
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		// Resize all screens, so that they fit when returning to them
+		return m.broadcast(m.screenSize())
+	case pushScreenMsg:
+		m.stack = append(m.stack, msg.model)
+		return m.initTop()
+	case replaceScreenMsg:
+		m.stack[len(m.stack)-1] = msg.model
+		return m.initTop()
+	case popScreenMsg:
+		if len(m.stack) == 1 {
+			return m, tea.Quit
+		}
+		m.stack = m.stack[:len(m.stack)-1]
+		return m, nil
+	case reconnecting:
+		model, cmd := m.broadcast(msg)
+		return model, tea.Batch(cmd, waitForRetry)
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		if c, ok := m.top().(inputCapturer); (!ok || !c.CapturesInput()) && msg.String() == "?" {
+			m.showHelp = !m.showHelp
+			return m, nil
+		}
+		if m.showHelp {
+			// Any other key closes the help
+			m.showHelp = false
+			return m, nil
+		}
+		return m.updateTop(msg)
+	case tea.MouseMsg:
+		return m.updateTop(msg)
+	}
+	// Other messages are results of commands, which may belong to a screen
+	// further down the stack
+	return m.broadcast(msg)
+}
+
+// initTop starts the screen which was just opened
+func (m MainModel) initTop() (tea.Model, tea.Cmd) {
+	initCmd := m.top().Init()
+	var cmd tea.Cmd
+	if m.width > 0 {
+		m.stack[len(m.stack)-1], cmd = m.top().Update(m.screenSize())
+	}
+	return m, tea.Batch(initCmd, cmd)
+}
+
+// updateTop passes the message to the active screen only
+func (m MainModel) updateTop(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	m.stack[len(m.stack)-1], cmd = m.top().Update(msg)
+	return m, cmd
+}
+
+// broadcast passes the message to all screens on the stack
+func (m MainModel) broadcast(msg tea.Msg) (tea.Model, tea.Cmd) {
+	cmds := make([]tea.Cmd, len(m.stack))
+	for i, screen := range m.stack {
+		m.stack[i], cmds[i] = screen.Update(msg)
+	}
+	return m, tea.Batch(cmds...)
 }
 
 // The main view, which just calls the appropriate sub-view
 func (m MainModel) View() string {
-	return m.rootModel.View()
+	if m.showHelp {
+		return m.breadcrumb() + "\n" + m.helpView()
+	}
+	return m.breadcrumb() + "\n" + m.top().View()
 }
 
-func (m MainModel) SwitchScreen(model tea.Model) (tea.Model, tea.Cmd) {
-	m.rootModel = model
-	return m, m.rootModel.Init()
+// breadcrumb renders the titles of all screens on the stack
+func (m MainModel) breadcrumb() string {
+	titles := make([]string, len(m.stack))
+	for i, screen := range m.stack {
+		titles[i] = "…"
+		if t, ok := screen.(titled); ok {
+			titles[i] = t.Title()
+		}
+	}
+	titles[len(titles)-1] = keywordStyle.Render(titles[len(titles)-1])
+	return breadcrumbStyle.Render(strings.Join(titles, " › "))
+}
+
+// helpView renders the global keys and the keys of the active screen
+func (m MainModel) helpView() string {
+	var s strings.Builder
+	writeKeys := func(title string, keys []keyHelp) {
+		s.WriteString(keywordStyle.Render(title) + "\n")
+		for _, k := range keys {
+			s.WriteString(fmt.Sprintf("  %-12s %s\n", k.key, subtleStyle.Render(k.desc)))
+		}
+	}
+	writeKeys("Global", globalHelp)
+	if h, ok := m.top().(helper); ok {
+		s.WriteString("\n")
+		writeKeys("This screen", h.Help())
+	}
+	return mainStyle.Render(overlayStyle.Render(strings.TrimSuffix(s.String(), "\n")))
 }
@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"jcli/jenkins"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+// configFileName is the file the config of a job or folder is exported to
+const configFileName = "config.xml"
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export [folder]",
+	Short: "Download the configs of all jobs in a folder",
+	Long: `Download the config.xml of every job and folder inside the folder, or on the
+whole server, into --dest. The directories mirror the folders: the config of
+team/app is written to team/app/config.xml. Jobs generated by multibranch
+projects and organization folders are not exported, only the project itself.
+
+With --script-only, only the pipeline scripts are written instead, the one
+of team/app as team/app.groovy. Sync can create the jobs from them again.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		folder := ""
+		if len(args) > 0 {
+			folder = strings.Trim(args[0], "/")
+		}
+		if err := exportJobs(os.Stdout, folder, exportDest); err != nil {
+			log.Fatal("Error: ", err)
+		}
+	},
+}
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <dir>",
+	Short: "Create the jobs of an exported directory on the server",
+	Long: `Create the folders and jobs of a directory written by export, with their
+config.xml, inside --folder or at the top level. Existing jobs are skipped
+unless --overwrite is given, which updates their config. Directories of
+exported scripts are imported with sync instead.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if failed, err := importJobs(os.Stdout, args[0], importFolder); err != nil {
+			log.Fatal("Error: ", err)
+		} else if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+var (
+	exportDest        string
+	exportScriptOnly  bool
+	exportConcurrency int
+	importFolder      string
+	importOverwrite   bool
+	importDryRun      bool
+)
+
+func init() {
+	rootCmd.AddCommand(exportCmd, importCmd)
+	exportCmd.Flags().StringVarP(&exportDest, "dest", "d", ".", "Directory to write the configs to.")
+	exportCmd.Flags().BoolVar(&exportScriptOnly, "script-only", false, "Only write the pipeline scripts, without the configs.")
+	exportCmd.Flags().IntVarP(&exportConcurrency, "concurrency", "c", 4, "How many configs are downloaded at the same time.")
+	importCmd.Flags().StringVarP(&importFolder, "folder", "f", "", "Folder to create the jobs in instead of the top level.")
+	importCmd.Flags().BoolVar(&importOverwrite, "overwrite", false, "Update the config of jobs which exist already.")
+	importCmd.Flags().BoolVarP(&importDryRun, "dry-run", "n", false, "Only list the jobs which would be created or updated.")
+}
+
+// exportItems lists the jobs and folders inside the folder, parents first.
+// The jobs of generating folders like multibranch projects are skipped.
+func exportItems(folder string) ([]jenkins.Item, error) {
+	items, err := Jenkins.ListItems(folder)
+	if err != nil {
+		return nil, err
+	}
+	var all []jenkins.Item
+	for _, item := range items {
+		all = append(all, item)
+		if !item.IsFolder() || item.GeneratesJobs() {
+			continue
+		}
+		children, err := exportItems(item.FullName)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, children...)
+	}
+	return all, nil
+}
+
+// exportJobs writes the configs or scripts of the jobs in the folder to
+// dest, relative to the folder
+func exportJobs(w io.Writer, folder, dest string) error {
+	items, err := exportItems(folder)
+	if err != nil {
+		return fmt.Errorf("could not list the jobs in %s: %w", folder, err)
+	}
+	var mu sync.Mutex
+	// Every job is exported, and all failures are returned together
+	errs := make([]error, len(items))
+	jobs, folders := 0, 0
+	var g errgroup.Group
+	g.SetLimit(max(exportConcurrency, 1))
+	for i, item := range items {
+		g.Go(func() error {
+			written, err := exportItem(item, folder, dest)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", item.FullName, err)
+				return nil
+			}
+			for _, file := range written {
+				fmt.Fprintln(w, file)
+			}
+			if item.IsFolder() {
+				folders++
+			} else {
+				jobs++
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	fmt.Fprintf(w, "Exported %d jobs and %d folders to %s\n", jobs, folders, dest)
+	return errors.Join(errs...)
+}
+
+// exportItem writes the config and script of a job and returns the files
+func exportItem(item jenkins.Item, folder, dest string) ([]string, error) {
+	rel := strings.TrimPrefix(strings.TrimPrefix(item.FullName, folder), "/")
+	dir := filepath.Join(dest, filepath.FromSlash(rel))
+	config, err := Jenkins.GetJobConfig(item.FullName)
+	if err != nil {
+		return nil, err
+	}
+	var written []string
+	if !exportScriptOnly {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		file := filepath.Join(dir, configFileName)
+		if err := os.WriteFile(file, []byte(config), 0o644); err != nil {
+			return nil, err
+		}
+		written = append(written, file)
+	}
+	if exportScriptOnly && !item.IsFolder() {
+		script, err := jenkins.ExtractPipelineScript(config)
+		if errors.Is(err, jenkins.ErrNoPipelineScript) {
+			// Jobs loading their Jenkinsfile from SCM have no script
+			return written, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+			return nil, err
+		}
+		file := dir + ".groovy"
+		if err := os.WriteFile(file, []byte(script), 0o644); err != nil {
+			return nil, err
+		}
+		written = append(written, file)
+	}
+	return written, nil
+}
+
+// exportedItems finds the directories with a config in an exported tree and
+// returns their job names relative to the tree, parents first
+func exportedItems(dir string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() != configFileName {
+			return err
+		}
+		rel, err := filepath.Rel(dir, filepath.Dir(file))
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			// The config of the exported folder itself
+			return nil
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Slice(names, func(i, j int) bool {
+		di, dj := strings.Count(names[i], "/"), strings.Count(names[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return names[i] < names[j]
+	})
+	return names, err
+}
+
+// importJobs creates the jobs and folders of an exported tree inside the
+// folder and returns how many could not be imported. Folders are imported
+// before their jobs.
+func importJobs(w io.Writer, dir, folder string) (int, error) {
+	names, err := exportedItems(dir)
+	if err != nil {
+		return 0, err
+	}
+	if len(names) == 0 {
+		return 0, fmt.Errorf("%s contains no %s files", dir, configFileName)
+	}
+	folder = strings.Trim(folder, "/")
+	if folder != "" && !importDryRun {
+		if err := Jenkins.CreateFolders(folder); err != nil {
+			return 0, err
+		}
+	}
+	created, updated, skipped, failed := 0, 0, 0, 0
+	verbs := []string{"Created", "Updated"}
+	if importDryRun {
+		verbs = []string{"Would create", "Would update"}
+	}
+	for _, name := range names {
+		jobName := path.Join(folder, name)
+		exists, err := Jenkins.JobExists(jobName)
+		if err == nil && exists && !importOverwrite {
+			fmt.Fprintln(w, "Skipped", jobName, "which exists already")
+			skipped++
+			continue
+		}
+		var config []byte
+		if err == nil {
+			config, err = os.ReadFile(filepath.Join(dir, filepath.FromSlash(name), configFileName))
+		}
+		if err == nil && !importDryRun {
+			if exists {
+				err = Jenkins.UpdateJobConfig(jobName, string(config))
+			} else {
+				err = Jenkins.CreateJob(jobName, string(config))
+			}
+		}
+		switch {
+		case err != nil:
+			fmt.Fprintln(w, "Error: Could not import", jobName+":", err)
+			failed++
+		case exists:
+			fmt.Fprintln(w, verbs[1], jobName)
+			updated++
+		default:
+			fmt.Fprintln(w, verbs[0], jobName)
+			created++
+		}
+	}
+	fmt.Fprintf(w, "Imported: %d created, %d updated, %d skipped", created, updated, skipped)
+	if failed > 0 {
+		fmt.Fprintf(w, ", %d failed", failed)
+	}
+	fmt.Fprintln(w)
+	return failed, nil
+}
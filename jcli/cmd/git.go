@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// git runs a git command in dir and returns its output lines
+func git(dir string, args ...string) ([]string, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// gitBranch returns the current branch of the git repository containing dir,
+// or an empty string if it is not in a repository
+func gitBranch(dir string) string {
+	lines, err := git(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil || len(lines) == 0 {
+		return ""
+	}
+	return lines[0]
+}
+
+// gitBranches returns the names of the local branches and of the branches
+// of the remotes without the name of the remote. The options are passed to
+// git for-each-ref.
+func gitBranches(dir string, options ...string) ([]string, error) {
+	args := append([]string{"for-each-ref", "--format=%(refname)"}, options...)
+	refs, err := git(dir, append(args, "refs/heads", "refs/remotes")...)
+	if err != nil {
+		return nil, err
+	}
+	var branches []string
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		var name string
+		switch {
+		case strings.HasPrefix(ref, "refs/heads/"):
+			name = strings.TrimPrefix(ref, "refs/heads/")
+		case strings.HasPrefix(ref, "refs/remotes/"):
+			// Strip the name of the remote
+			_, name, _ = strings.Cut(strings.TrimPrefix(ref, "refs/remotes/"), "/")
+		}
+		if name == "" || name == "HEAD" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		branches = append(branches, name)
+	}
+	return branches, nil
+}
+
+// gitMergedBranches returns the branches which are merged into base
+func gitMergedBranches(dir, base string) ([]string, error) {
+	return gitBranches(dir, "--merged", base)
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"jcli/jenkins"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// jobCmd represents the job command
+var jobCmd = &cobra.Command{
+	Use:   "job",
+	Short: "Enable, disable, delete, rename, copy or change jobs",
+	Long: `Manage the lifecycle of jobs. Deleting and renaming ask for confirmation
+unless --yes is given. With --output json or yaml, the results are printed in
+a form scripts can read.`,
+}
+
+var jobEnableCmd = &cobra.Command{
+	Use:   "enable <job>...",
+	Short: "Allow jobs to build again",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runJobAction(args, "enabled", "", Jenkins.EnableJob)
+	},
+}
+
+var jobDisableCmd = &cobra.Command{
+	Use:   "disable <job>...",
+	Short: "Stop jobs from building until they are enabled again",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runJobAction(args, "disabled", "", Jenkins.DisableJob)
+	},
+}
+
+var jobDeleteCmd = &cobra.Command{
+	Use:   "delete <job>...",
+	Short: "Delete jobs or folders with all their builds",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runJobAction(args, "deleted", "Delete %s with all its builds?", Jenkins.DeleteJob)
+	},
+}
+
+var jobRenameCmd = &cobra.Command{
+	Use:   "rename <job> <new name>",
+	Short: "Rename a job inside its folder",
+	Long: `Rename a job inside its folder. The new name is a plain name without the
+folder. Links to the job and its builds break, so jobs using it, like
+upstream triggers, have to be updated.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkOutputFormat(jobOutput)
+		jobName, newName := strings.Trim(args[0], "/"), args[1]
+		renamed := path.Join(jenkins.ParentFolder(jobName), newName)
+		result := jobResult{Job: renamed, Action: "renamed", From: jobName}
+		if !confirmAction(fmt.Sprintf("Rename %s to %s?", jobName, renamed), jobYes) {
+			result.Job, result.Action = jobName, "skipped"
+		} else if err := Jenkins.RenameJob(jobName, newName); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Url = Jenkins.JobUrl(renamed) + "/"
+		}
+		writeJobResults([]jobResult{result})
+	},
+}
+
+var jobCopyCmd = &cobra.Command{
+	Use:   "copy <job> <new job>",
+	Short: "Create a job with the config of another one",
+	Long: `Create a job with the config of another one. The new job may be in another
+folder, missing folders are created.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkOutputFormat(jobOutput)
+		jobName, newName := strings.Trim(args[0], "/"), strings.Trim(args[1], "/")
+		result := jobResult{Job: newName, Action: "copied", From: jobName}
+		err := Jenkins.CreateFolders(jenkins.ParentFolder(newName))
+		if err == nil {
+			err = Jenkins.CopyJob(jobName, newName)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Url = Jenkins.JobUrl(newName) + "/"
+		}
+		writeJobResults([]jobResult{result})
+	},
+}
+
+var (
+	jobOutput string
+	jobYes    bool
+)
+
+func init() {
+	rootCmd.AddCommand(jobCmd)
+	jobCmd.AddCommand(jobEnableCmd, jobDisableCmd, jobDeleteCmd, jobRenameCmd, jobCopyCmd)
+	jobCmd.PersistentFlags().StringVarP(&jobOutput, "output", "o", "text", "Output format: text, json or yaml.")
+	jobCmd.PersistentFlags().BoolVarP(&jobYes, "yes", "y", false, "Do not ask for confirmation.")
+}
+
+// jobResult is the outcome of a job command for one job
+type jobResult struct {
+	Job    string `json:"job" yaml:"job"`
+	Action string `json:"action" yaml:"action"`
+	// From is the original job of renamed and copied jobs
+	From  string `json:"from,omitempty" yaml:"from,omitempty"`
+	Url   string `json:"url,omitempty" yaml:"url,omitempty"`
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// jobVerbs are the verbs of the actions in error messages
+var jobVerbs = map[string]string{
+	"enabled":  "enable",
+	"disabled": "disable",
+	"deleted":  "delete",
+	"renamed":  "rename",
+	"copied":   "copy",
+	"updated":  "update",
+}
+
+// runJobAction runs the action for each job, after asking the question if
+// it is not empty, and prints the results
+func runJobAction(jobs []string, action, question string, run func(jobName string) error) {
+	checkOutputFormat(jobOutput)
+	var results []jobResult
+	for _, jobName := range jobs {
+		jobName = strings.Trim(jobName, "/")
+		result := jobResult{Job: jobName, Action: action}
+		if question != "" && !confirmAction(fmt.Sprintf(question, jobName), jobYes) {
+			result.Action = "skipped"
+			results = append(results, result)
+			continue
+		}
+		if err := run(jobName); err != nil {
+			result.Error = err.Error()
+		} else if action != "deleted" {
+			result.Url = Jenkins.JobUrl(jobName) + "/"
+		}
+		results = append(results, result)
+	}
+	writeJobResults(results)
+}
+
+// checkOutputFormat exits if the output format is unknown
+func checkOutputFormat(format string) {
+	switch format {
+	case "text", "json", "yaml":
+		return
+	}
+	log.Fatal("Error: Unknown output format ", format, ", use text, json or yaml")
+}
+
+// writeJobResults prints the results in the output format and exits with
+// an error if any job failed
+func writeJobResults(results []jobResult) {
+	checkOutputFormat(jobOutput)
+	if err := writeOutput(os.Stdout, jobOutput, results, func(w io.Writer) {
+		for _, r := range results {
+			switch {
+			case r.Error != "":
+				fmt.Fprintf(w, "Error: Could not %s %s: %s\n", jobVerbs[r.Action], r.Job, r.Error)
+			case r.Action == "skipped":
+				fmt.Fprintln(w, "Skipped", r.Job)
+			case r.From != "":
+				fmt.Fprintf(w, "%s %s to %s\n", capitalize(r.Action), r.From, r.Job)
+			default:
+				fmt.Fprintln(w, capitalize(r.Action), r.Job)
+			}
+		}
+	}); err != nil {
+		log.Fatal("Error: ", err)
+	}
+	for _, r := range results {
+		if r.Error != "" {
+			os.Exit(1)
+		}
+	}
+}
+
+// writeOutput writes v as JSON or YAML, or calls text for the text format
+func writeOutput(w io.Writer, format string, v any, text func(w io.Writer)) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(v)
+	}
+	text(w)
+	return nil
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
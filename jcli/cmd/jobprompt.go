@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxJobMatches is the number of matching jobs listed below the prompt
+const maxJobMatches = 8
+
+type jobList struct {
+	jobs []string
+	err  error
+}
+
+// JobPromptModel asks for the job of a pipeline file which is not mapped in
+// the project config yet. Existing jobs are suggested while typing, a new
+// name creates the job.
+type JobPromptModel struct {
+	file    string
+	input   textinput.Model
+	jobs    []string
+	matches []string
+	// cursor is the selected match, or -1 for the typed name
+	cursor        int
+	save          bool
+	message       string
+	width, height int
+}
+
+func NewJobPromptModel(file string, width, height int) *JobPromptModel {
+	ti := textinput.New()
+	ti.Prompt = "Job: "
+	ti.Placeholder = "folder/job"
+	ti.SetValue(suggestedJob(file))
+	ti.CursorEnd()
+	ti.Focus()
+	return &JobPromptModel{
+		file:    file,
+		input:   ti,
+		cursor:  -1,
+		save:    true,
+		message: "Loading jobs...",
+		width:   width,
+		height:  height,
+	}
+}
+
+func (m *JobPromptModel) Init() tea.Cmd {
+	return tea.Batch(textinput.Blink, func() tea.Msg {
+		items, err := Jenkins.ListJobs("")
+		names := make([]string, len(items))
+		for i, item := range items {
+			names[i] = item.FullName
+		}
+		return jobList{jobs: names, err: err}
+	})
+}
+
+// updateMatches lists the jobs containing the typed name
+func (m *JobPromptModel) updateMatches() {
+	query := strings.ToLower(m.input.Value())
+	m.matches = m.matches[:0]
+	for _, job := range m.jobs {
+		if len(m.matches) == maxJobMatches {
+			break
+		}
+		if strings.Contains(strings.ToLower(job), query) {
+			m.matches = append(m.matches, job)
+		}
+	}
+	m.cursor = min(m.cursor, len(m.matches)-1)
+}
+
+// selected returns the chosen job name
+func (m *JobPromptModel) selected() string {
+	if m.cursor >= 0 {
+		return m.matches[m.cursor]
+	}
+	return strings.Trim(strings.TrimSpace(m.input.Value()), "/")
+}
+
+// exists reports whether the job is on the server
+func (m *JobPromptModel) exists(job string) bool {
+	for _, j := range m.jobs {
+		if j == job {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *JobPromptModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+	case jobList:
+		if msg.err != nil {
+			log.Println("Error:", msg.err)
+			m.message = "⚠️ Could not list jobs: " + msg.err.Error()
+			return m, nil
+		}
+		m.message = ""
+		m.jobs = msg.jobs
+		m.updateMatches()
+		return m, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			return m, popScreen
+		case "up", "ctrl+p":
+			m.cursor = max(m.cursor-1, -1)
+			return m, nil
+		case "down", "ctrl+n":
+			m.cursor = min(m.cursor+1, len(m.matches)-1)
+			return m, nil
+		case "tab": // Complete the selected job
+			if m.cursor >= 0 {
+				m.input.SetValue(m.matches[m.cursor])
+				m.input.CursorEnd()
+				m.cursor = -1
+				m.updateMatches()
+			}
+			return m, nil
+		case "ctrl+s":
+			m.save = !m.save
+			return m, nil
+		case "enter":
+			job := m.selected()
+			if job == "" {
+				return m, nil
+			}
+			if m.save {
+				if err := saveJobMapping(m.file, job); err != nil {
+					log.Println("Error:", err)
+					m.message = "⚠️ Could not save the mapping: " + err.Error()
+					return m, nil
+				}
+			}
+			build := NewBuildModel(m.file, job, m.width, m.height)
+			if err := applyUpdateFlags(build); err != nil {
+				log.Println("Error:", err)
+				m.message = "⚠️ " + err.Error()
+				return m, nil
+			}
+			return m, replaceScreen(confirmUpdate(build))
+		}
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	if _, ok := msg.(tea.KeyMsg); ok {
+		m.cursor = -1
+		m.updateMatches()
+	}
+	return m, cmd
+}
+
+func (m *JobPromptModel) Title() string {
+	return "Choose job"
+}
+
+func (m *JobPromptModel) Help() []keyHelp {
+	return []keyHelp{
+		{"enter", "update the job and build the file"},
+		{"↑/↓", "select an existing job"},
+		{"tab", "complete the selected job"},
+		{"ctrl+s", "toggle saving the mapping"},
+		{"esc", "cancel"},
+	}
+}
+
+// CapturesInput is always true, the prompt reads the job name
+func (m *JobPromptModel) CapturesInput() bool {
+	return true
+}
+
+func (m *JobPromptModel) View() string {
+	var s strings.Builder
+	s.WriteString("\n" + keywordStyle.Render(m.file) + subtleStyle.Render(" is not mapped to a job yet") + "\n\n")
+	s.WriteString(m.input.View() + "\n\n")
+	for i, job := range m.matches {
+		if i == m.cursor {
+			s.WriteString(checkboxStyle.Render("> "+job) + "\n")
+		} else {
+			s.WriteString("  " + job + "\n")
+		}
+	}
+	if job := m.selected(); job != "" && m.jobs != nil && !m.exists(job) {
+		s.WriteString(subtleStyle.Render(fmt.Sprintf("\n%s does not exist and will be created", job)) + "\n")
+	}
+	check := "[ ]"
+	if m.save {
+		check = "[x]"
+	}
+	s.WriteString("\n" + checkboxStyle.Render(check) + " Save mapping to " + Config.ProjectPath() + "\n")
+	if m.message != "" {
+		s.WriteString("\n" + m.message + "\n")
+	}
+	help := helpStyle.Render("\n enter: build • ↑/↓: select • tab: complete • ctrl+s: save mapping • esc: cancel\n")
+	return mainStyle.Render(s.String()) + help
+}
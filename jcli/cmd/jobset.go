@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"jcli/diff"
+	"jcli/jenkins"
+
+	"github.com/spf13/cobra"
+)
+
+var jobSetCmd = &cobra.Command{
+	Use:   "set <job>...",
+	Short: "Change the settings of jobs",
+	Long: `Change the description, build retention, concurrency, triggers, sandbox or
+parameters of jobs without editing their config.xml. Only the given settings
+are changed. The changes are shown and confirmed before the config is saved,
+unless --yes is given.
+
+Parameters are given as NAME=DEFAULT, choice parameters as NAME=a,b,c with the
+first choice as default. A parameter with the name of an existing one replaces
+it. An empty --cron or --poll-scm removes the trigger, --keep-builds 0 removes
+the limit of builds.`,
+	Example: `  jcli job set team/app --keep-builds 20 --disable-concurrent
+  jcli job set team/app --cron "H 2 * * *" --choice-param ENV=dev,staging,prod
+  jcli job set team/app --remove-param DEBUG --sandbox=false`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkOutputFormat(jobOutput)
+		edits, err := jobSettings(cmd)
+		if err != nil {
+			log.Fatal("Error: ", err)
+		}
+		var results []jobResult
+		for _, jobName := range args {
+			results = append(results, setJob(strings.Trim(jobName, "/"), edits))
+		}
+		writeJobResults(results)
+	},
+}
+
+var (
+	setDescription       string
+	setKeepBuilds        int
+	setDisableConcurrent bool
+	setCron              string
+	setPollSCM           string
+	setSandbox           bool
+	setStringParams      []string
+	setChoiceParams      []string
+	setBoolParams        []string
+	setRemoveParams      []string
+)
+
+func init() {
+	jobCmd.AddCommand(jobSetCmd)
+	flags := jobSetCmd.Flags()
+	flags.StringVar(&setDescription, "description", "", "Description of the job.")
+	flags.IntVar(&setKeepBuilds, "keep-builds", 0, "Discard all but this many builds, 0 removes the limit.")
+	flags.BoolVar(&setDisableConcurrent, "disable-concurrent", false, "Do not run builds of the job at the same time.")
+	flags.StringVar(&setCron, "cron", "", "Cron spec to build the job periodically with, like \"H 2 * * *\".")
+	flags.StringVar(&setPollSCM, "poll-scm", "", "Cron spec to poll the SCM for changes with.")
+	flags.BoolVar(&setSandbox, "sandbox", true, "Run the pipeline script in the Groovy sandbox.")
+	flags.StringArrayVar(&setStringParams, "string-param", nil, "Add a string parameter as NAME=DEFAULT.")
+	flags.StringArrayVar(&setChoiceParams, "choice-param", nil, "Add a choice parameter as NAME=a,b,c.")
+	flags.StringArrayVar(&setBoolParams, "bool-param", nil, "Add a boolean parameter as NAME=true or NAME=false.")
+	flags.StringArrayVar(&setRemoveParams, "remove-param", nil, "Remove the parameter with the name.")
+}
+
+// jobSettings returns the changes of the given flags to a job config
+func jobSettings(cmd *cobra.Command) ([]func(c *jenkins.JobConfig) error, error) {
+	var edits []func(c *jenkins.JobConfig) error
+	flags := cmd.Flags()
+	if flags.Changed("description") {
+		edits = append(edits, func(c *jenkins.JobConfig) error {
+			c.SetDescription(setDescription)
+			return nil
+		})
+	}
+	if flags.Changed("keep-builds") {
+		if setKeepBuilds < 0 {
+			return nil, fmt.Errorf("--keep-builds must not be negative")
+		}
+		edits = append(edits, func(c *jenkins.JobConfig) error {
+			c.SetKeepBuilds(setKeepBuilds)
+			return nil
+		})
+	}
+	if flags.Changed("disable-concurrent") {
+		edits = append(edits, func(c *jenkins.JobConfig) error {
+			c.SetConcurrentBuilds(!setDisableConcurrent)
+			return nil
+		})
+	}
+	if flags.Changed("cron") {
+		edits = append(edits, func(c *jenkins.JobConfig) error {
+			c.SetCronTrigger(setCron)
+			return nil
+		})
+	}
+	if flags.Changed("poll-scm") {
+		edits = append(edits, func(c *jenkins.JobConfig) error {
+			c.SetPollSCMTrigger(setPollSCM)
+			return nil
+		})
+	}
+	if flags.Changed("sandbox") {
+		edits = append(edits, func(c *jenkins.JobConfig) error {
+			return c.SetSandbox(setSandbox)
+		})
+	}
+	for _, name := range setRemoveParams {
+		edits = append(edits, func(c *jenkins.JobConfig) error {
+			c.RemoveParameter(name)
+			return nil
+		})
+	}
+	params := map[jenkins.ParameterType][]string{
+		jenkins.StringParameter:  setStringParams,
+		jenkins.ChoiceParameter:  setChoiceParams,
+		jenkins.BooleanParameter: setBoolParams,
+	}
+	for _, typ := range []jenkins.ParameterType{jenkins.StringParameter, jenkins.ChoiceParameter, jenkins.BooleanParameter} {
+		for _, value := range params[typ] {
+			p := parseParameter(typ, value)
+			// Check the parameter before contacting the server
+			if err := p.Validate(); err != nil {
+				return nil, err
+			}
+			edits = append(edits, func(c *jenkins.JobConfig) error {
+				return c.SetParameter(p)
+			})
+		}
+	}
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("no settings given, see jcli job set --help")
+	}
+	return edits, nil
+}
+
+// parseParameter parses a parameter flag of the form NAME=DEFAULT, where
+// the default of choice parameters is the list of choices
+func parseParameter(typ jenkins.ParameterType, value string) jenkins.Parameter {
+	name, value, _ := strings.Cut(value, "=")
+	p := jenkins.Parameter{Type: typ, Name: strings.TrimSpace(name), DefaultValue: value}
+	if typ == jenkins.ChoiceParameter {
+		p.DefaultValue = ""
+		for _, choice := range strings.Split(value, ",") {
+			if choice = strings.TrimSpace(choice); choice != "" {
+				p.Choices = append(p.Choices, choice)
+			}
+		}
+	}
+	return p
+}
+
+// setJob applies the changes to the config of the job, after showing and
+// confirming them
+func setJob(jobName string, edits []func(c *jenkins.JobConfig) error) jobResult {
+	result := jobResult{Job: jobName, Action: "updated"}
+	fail := func(err error) jobResult {
+		result.Error = err.Error()
+		return result
+	}
+	current, err := Jenkins.GetJobConfig(jobName)
+	if err != nil {
+		return fail(err)
+	}
+	config, err := jenkins.ParseJobConfig(current)
+	if err != nil {
+		return fail(err)
+	}
+	for _, edit := range edits {
+		if err := edit(config); err != nil {
+			return fail(err)
+		}
+	}
+	updated, err := config.String()
+	if err != nil {
+		return fail(err)
+	}
+	// Compare the normalized configs to ignore the formatting
+	before, err := jenkins.NormalizeConfig(current)
+	if err != nil {
+		return fail(err)
+	}
+	after, err := jenkins.NormalizeConfig(updated)
+	if err != nil {
+		return fail(err)
+	}
+	if before == after {
+		result.Action = "unchanged"
+		return result
+	}
+	if !jobYes {
+		fmt.Fprint(os.Stderr, colorDiff(diff.Unified(jobName+" (server)", jobName+" (changed)", before, after, 3)))
+	}
+	if !confirmAction(fmt.Sprintf("Update the config of %s?", jobName), jobYes) {
+		result.Action = "skipped"
+		return result
+	}
+	if err := Jenkins.UpdateJobConfig(jobName, updated); err != nil {
+		return fail(err)
+	}
+	result.Url = Jenkins.JobUrl(jobName) + "/"
+	return result
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"jcli/jenkins"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	logsFollow bool
+	logsPlain  bool
+	logsSince  int64
+	logsTail   int
+	logsHtml   bool
+	logsFilter bool
+	logsSave   bool
+	logsSaveAs string
+	logsFormat string
+	logsGzip   bool
+)
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs <job> [build|lastBuild]",
+	Short: "Show the console log of an existing build",
+	Long: `Shows the console log of a build without triggering a new one.
+The build is given by its number or a permalink like lastBuild or
+lastFailedBuild and defaults to lastBuild.
+
+The log opens in the interactive viewer, which follows the build until it
+finishes. With --plain, or when the output is not a terminal, the log is
+written as plain text so it can be piped into other tools.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		jobName := args[0]
+		ref := ""
+		if len(args) > 1 {
+			ref = args[1]
+		}
+		buildUrl, err := Jenkins.ResolveBuildUrl(jobName, ref)
+		if err != nil {
+			log.Fatal("Error: Could not find build ", Jenkins.BuildUrl(jobName, ref), ": ", err)
+		}
+
+		if logsSave || logsSaveAs != "" {
+			path, err := saveBuildLog(jobName, buildUrl, logsSaveAs, logsFormat, logsGzip)
+			if err != nil {
+				log.Fatal("Error: Could not save the console log: ", err)
+			}
+			fmt.Println("Saved log to", path)
+			return
+		}
+
+		if logsPlain || !term.IsTerminal(int(os.Stdout.Fd())) {
+			if err := printBuildLog(buildUrl); err != nil {
+				log.Fatal("Error: Could not read the console log: ", err)
+			}
+			return
+		}
+
+		f, err := tea.LogToFile("lazyjenkins.log", "logs")
+		if err != nil {
+			fmt.Println("fatal:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		m := NewBuildModelFromUrl(jobName, buildUrl, 80, 24)
+		m.logStart = logsSince
+		m.logTail = logsTail
+		m.useHtml = logsHtml
+		p := tea.NewProgram(NewMainModel(m), tea.WithMouseCellMotion(), tea.WithAltScreen())
+		if _, err := p.Run(); err != nil {
+			fmt.Println("could not start program:", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep streaming the log until the build finishes.")
+	logsCmd.Flags().BoolVarP(&logsPlain, "plain", "p", false, "Write the log as plain text instead of opening the viewer.")
+	logsCmd.Flags().Int64Var(&logsSince, "since", 0, "Start at the given byte offset of the log, as reported by X-Text-Size.")
+	logsCmd.Flags().IntVarP(&logsTail, "tail", "n", 0, "Only show the last N lines of the log.")
+	logsCmd.Flags().BoolVar(&logsFilter, "filter", false, "Apply the log filter of the config file to the plain text log.")
+	logsCmd.Flags().BoolVar(&logsSave, "save", false, "Save the full, unfiltered log to a file named after the job and build number.")
+	logsCmd.Flags().StringVar(&logsSaveAs, "save-as", "", "Save the full, unfiltered log to this file.")
+	logsCmd.Flags().StringVar(&logsFormat, "format", "text", "Format of the saved log: text or html.")
+	logsCmd.Flags().BoolVar(&logsGzip, "gzip", false, "Compress the saved log with gzip.")
+	logsCmd.Flags().BoolVar(&logsHtml, "html", false, "Fetch the log rendered as HTML by Jenkins, which includes the links of console annotations.")
+}
+
+// printBuildLog writes the console log of the build to stdout and keeps
+// streaming new output while the build runs if --follow is set
+func printBuildLog(buildUrl string) error {
+	var filter *jenkins.LogFilter
+	if logsFilter {
+		var err error
+		if filter, err = newLogFilter(); err != nil {
+			return err
+		}
+	}
+	text, offset, moreData, err := fetchPlainLog(buildUrl, logsSince)
+	if err != nil {
+		return err
+	}
+	// Only complete lines are filtered, the rest is kept for the next poll
+	text, pending := splitPartialLine(text, moreData && logsFollow)
+	fmt.Print(tailLines(filter.Apply(text), logsTail))
+	for logsFollow && moreData {
+		time.Sleep(1 * time.Second)
+		text, offset, moreData, err = fetchPlainLog(buildUrl, offset)
+		if err != nil {
+			return err
+		}
+		text, pending = splitPartialLine(pending+text, moreData)
+		fmt.Print(filter.Apply(text))
+	}
+	return nil
+}
+
+// splitPartialLine splits off an incomplete last line of the text if more
+// text follows
+func splitPartialLine(text string, moreData bool) (string, string) {
+	if !moreData {
+		return text, ""
+	}
+	idx := strings.LastIndex(text, "\n")
+	return text[:idx+1], text[idx+1:]
+}
+
+// fetchPlainLog fetches the log starting at offset without the encoded
+// console notes. Colors are only kept when writing to a terminal.
+func fetchPlainLog(buildUrl string, offset int64) (string, int64, bool, error) {
+	var text string
+	var moreData bool
+	var err error
+	if logsHtml {
+		text, offset, moreData, err = Jenkins.GetProgressiveHtml(buildUrl, offset)
+		text = jenkins.HTMLToANSI(text, buildUrl, true)
+	} else {
+		text, offset, moreData, err = Jenkins.GetProgressiveText(buildUrl, offset)
+		text = jenkins.StripConsoleNotes(text)
+	}
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		text = jenkins.StripANSI(text)
+	}
+	return text, offset, moreData, err
+}
+
+// tailLines returns the last n lines of text, or all of it if n is not positive
+func tailLines(text string, n int) string {
+	if n <= 0 {
+		return text
+	}
+	lines := strings.SplitAfter(text, "\n")
+	// A trailing newline leaves an empty last element
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= n {
+		return text
+	}
+	return strings.Join(lines[len(lines)-n:], "")
+}
+
+// saveBuildLog writes the full, unfiltered console log of the build to path
+// and returns the path. The text format strips the colors, the html format
+// keeps them. An empty path names the file after the job and build number.
+func saveBuildLog(jobName, buildUrl, path, format string, compress bool) (string, error) {
+	build, err := Jenkins.GetBuild(buildUrl)
+	if err != nil {
+		return "", err
+	}
+	text, _, _, err := Jenkins.GetProgressiveText(buildUrl, 0)
+	if err != nil {
+		return "", err
+	}
+	text = jenkins.StripConsoleNotes(text)
+
+	var content, ext string
+	switch format {
+	case "text":
+		content, ext = jenkins.StripANSI(text), ".log"
+	case "html":
+		title := fmt.Sprintf("%s #%d", jobName, build.Number)
+		content, ext = jenkins.ANSIToHTML(text, title), ".html"
+	default:
+		return "", fmt.Errorf("unknown log format %q, use text or html", format)
+	}
+	if path == "" {
+		path = fmt.Sprintf("%s-%d%s", strings.ReplaceAll(jobName, "/", "_"), build.Number, ext)
+		if compress {
+			path += ".gz"
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if compress {
+		gz := gzip.NewWriter(f)
+		if _, err := io.WriteString(gz, content); err != nil {
+			return "", err
+		}
+		if err := gz.Close(); err != nil {
+			return "", err
+		}
+	} else if _, err := io.WriteString(f, content); err != nil {
+		return "", err
+	}
+	return path, f.Close()
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+
+	"jcli/config"
+)
+
+// jobVars returns the template variables of a pipeline file
+func jobVars(file string) config.JobVars {
+	vars, err := Config.FileVars(file)
+	if err != nil {
+		base := filepath.Base(file)
+		vars.Base = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	vars.Branch = gitBranch(filepath.Dir(file))
+	vars.User = User
+	return vars
+}
+
+// mappedJob returns the job the pipeline file is mapped to in the project
+// config. It reports false if the file is not mapped.
+func mappedJob(file string) (string, bool) {
+	job, ok, err := Config.MapJob(file, jobVars(file))
+	if err != nil {
+		log.Println("Error:", err)
+	}
+	return job, ok
+}
+
+// suggestedJob returns the job name proposed for a file without mapping
+func suggestedJob(file string) string {
+	return jobVars(file).Base
+}
+
+// saveJobMapping maps the file to the job in the project config
+func saveJobMapping(file, jobName string) error {
+	rel, err := Config.RelativePath(file)
+	if err != nil {
+		return err
+	}
+	return Config.SaveJobMapping(config.JobMapping{File: rel, Job: jobName})
+}
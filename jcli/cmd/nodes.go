@@ -0,0 +1,320 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"jcli/jenkins"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+var (
+	nodesTui    bool
+	nodesReason string
+)
+
+// nodesCmd represents the nodes command
+var nodesCmd = &cobra.Command{
+	Use:   "nodes",
+	Short: "List the agents with their state, labels and running builds",
+	Long: `Lists all nodes of the server with their state, labels, how many of their
+executors are busy, the builds running on them and why they are offline.
+
+With --tui the nodes are shown in an interactive table which refreshes
+itself. The offline and online commands take the names listed, the built-in
+node is also found as (built-in).`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !nodesTui {
+			nodes, err := Jenkins.GetNodes()
+			if err != nil {
+				log.Fatal("Error: Could not get nodes: ", err)
+			}
+			printNodes(nodes)
+			return
+		}
+
+		f, err := tea.LogToFile("lazyjenkins.log", "nodes")
+		if err != nil {
+			fmt.Println("fatal:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		p := tea.NewProgram(NewMainModel(NewNodesModel(80, 24)), tea.WithAltScreen())
+		if _, err := p.Run(); err != nil {
+			fmt.Println("could not start program:", err)
+		}
+	},
+}
+
+var nodesOfflineCmd = &cobra.Command{
+	Use:   "offline <name>",
+	Short: "Take a node offline, or change the reason it is offline",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := Jenkins.SetNodeOffline(args[0], nodesReason); err != nil {
+			log.Fatal("Error: Could not take node ", args[0], " offline: ", err)
+		}
+		fmt.Println("Took", args[0], "offline")
+	},
+}
+
+var nodesOnlineCmd = &cobra.Command{
+	Use:   "online <name>",
+	Short: "Bring a node back online which was taken offline",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := Jenkins.SetNodeOnline(args[0]); err != nil {
+			log.Fatal("Error: Could not bring node ", args[0], " online: ", err)
+		}
+		fmt.Println("Brought", args[0], "online")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(nodesCmd)
+	nodesCmd.AddCommand(nodesOfflineCmd, nodesOnlineCmd)
+
+	nodesCmd.Flags().BoolVarP(&nodesTui, "tui", "i", false, "Browse the nodes interactively.")
+	nodesOfflineCmd.Flags().StringVarP(&nodesReason, "reason", "r", "", "Why the node is offline, shown to other users.")
+}
+
+var nodeColumns = []string{"NAME", "STATE", "EXECUTORS", "LABELS", "RUNNING", "REASON"}
+
+// nodeRow returns the columns shown for a node
+func nodeRow(n jenkins.Node) []string {
+	var running []string
+	for _, e := range n.Running() {
+		running = append(running, e.FullDisplayName)
+	}
+	return []string{
+		n.DisplayName,
+		n.State(),
+		fmt.Sprintf("%d/%d", n.Busy(), n.NumExecutors),
+		strings.Join(n.Labels(), " "),
+		strings.Join(running, ", "),
+		strings.Join(strings.Fields(n.OfflineCauseReason), " "),
+	}
+}
+
+// printNodes prints the nodes as a plain table to stdout
+func printNodes(nodes []jenkins.Node) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(nodeColumns, "\t"))
+	for _, n := range nodes {
+		fmt.Fprintln(w, strings.Join(nodeRow(n), "\t"))
+	}
+	w.Flush()
+}
+
+// nodesData, nodesTick and nodesMessage are tagged with the screen they
+// belong to, messages are broadcast to all screens
+type nodesData struct {
+	model *NodesModel
+	nodes []jenkins.Node
+	err   error
+}
+type nodesTick struct{ model *NodesModel }
+type nodesMessage struct {
+	model *NodesModel
+	text  string
+}
+
+// NodesModel shows the nodes in a table, which is refreshed periodically,
+// and the builds running on the selected node
+type NodesModel struct {
+	nodes         []jenkins.Node
+	table         table.Model
+	interval      time.Duration
+	message       string
+	width, height int
+}
+
+func NewNodesModel(width, height int) *NodesModel {
+	widths := []int{24, 9, 9, 24, 30, 24}
+	columns := make([]table.Column, len(nodeColumns))
+	for i, title := range nodeColumns {
+		columns[i] = table.Column{Title: title, Width: widths[i]}
+	}
+	t := table.New(
+		table.WithColumns(columns),
+		table.WithFocused(true),
+		table.WithHeight(max(height-14, 3)),
+	)
+	t.SetStyles(tableStyles())
+	return &NodesModel{
+		table:    t,
+		interval: Config.Dashboard.Interval,
+		message:  "Loading nodes...",
+		width:    width,
+		height:   height,
+	}
+}
+
+func (m *NodesModel) Init() tea.Cmd {
+	return tea.Batch(m.poll(), m.tick())
+}
+
+// tick schedules the next refresh
+func (m *NodesModel) tick() tea.Cmd {
+	return tea.Tick(m.interval, func(time.Time) tea.Msg { return nodesTick{model: m} })
+}
+
+// poll fetches the state of all nodes
+func (m *NodesModel) poll() tea.Cmd {
+	return func() tea.Msg {
+		nodes, err := Jenkins.GetNodes()
+		return nodesData{model: m, nodes: nodes, err: err}
+	}
+}
+
+// selected returns the node under the cursor
+func (m *NodesModel) selected() *jenkins.Node {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.nodes) {
+		return nil
+	}
+	return &m.nodes[cursor]
+}
+
+func (m *NodesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.table.SetHeight(max(m.height-14, 3))
+	case nodesData:
+		if msg.model != m {
+			return m, nil
+		}
+		if msg.err != nil {
+			log.Println("Error:", msg.err)
+			m.message = "⚠️ Could not get nodes: " + msg.err.Error()
+			return m, nil
+		}
+		m.nodes = msg.nodes
+		rows := make([]table.Row, len(m.nodes))
+		for i, n := range m.nodes {
+			rows[i] = nodeRow(n)
+		}
+		m.table.SetRows(rows)
+		if strings.HasPrefix(m.message, "Loading") {
+			m.message = ""
+		}
+		return m, nil
+	case nodesTick:
+		if msg.model != m {
+			return m, nil
+		}
+		return m, tea.Batch(m.poll(), m.tick())
+	case nodesMessage:
+		if msg.model != m {
+			return m, nil
+		}
+		m.message = msg.text
+		return m, nil
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc", "q":
+			return m, popScreen
+		case "r": // Refresh now
+			return m, m.poll()
+		case "x": // Take the node offline or bring it back online
+			if n := m.selected(); n != nil {
+				return m, m.toggleOffline(*n)
+			}
+		case "o": // Open the node in the browser
+			if n := m.selected(); n != nil {
+				jenkins.Openbrowser(Jenkins.NodeUrl(n.Name()))
+			}
+		case "enter", "l": // Show the log of the build running on the node
+			n := m.selected()
+			if n == nil {
+				return m, nil
+			}
+			running := n.Running()
+			if len(running) == 0 {
+				m.message = n.DisplayName + " is idle"
+				return m, nil
+			}
+			buildUrl := running[0].Url
+			return m, pushScreen(NewBuildModelFromUrl(jenkins.JobNameFromUrl(buildUrl), buildUrl, m.width, m.height))
+		}
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+// toggleOffline takes an online node offline and brings a node which was
+// taken offline back online, then refreshes the nodes
+func (m *NodesModel) toggleOffline(n jenkins.Node) tea.Cmd {
+	return tea.Sequence(func() tea.Msg {
+		if n.TemporarilyOffline {
+			if err := Jenkins.SetNodeOnline(n.Name()); err != nil {
+				log.Println("Error:", err)
+				return nodesMessage{model: m, text: "⚠️ Could not bring " + n.DisplayName + " online: " + err.Error()}
+			}
+			return nodesMessage{model: m, text: "🟢 Brought " + n.DisplayName + " online"}
+		}
+		if err := Jenkins.SetNodeOffline(n.Name(), "Taken offline by "+User+" with jcli"); err != nil {
+			log.Println("Error:", err)
+			return nodesMessage{model: m, text: "⚠️ Could not take " + n.DisplayName + " offline: " + err.Error()}
+		}
+		return nodesMessage{model: m, text: "🔴 Took " + n.DisplayName + " offline"}
+	}, m.poll())
+}
+
+func (m *NodesModel) Title() string {
+	return "Nodes"
+}
+
+func (m *NodesModel) Help() []keyHelp {
+	return []keyHelp{
+		{"enter/l", "show the log of the build running on the node"},
+		{"x", "take the node offline or bring it online"},
+		{"o", "open the node in the browser"},
+		{"r", "refresh now"},
+	}
+}
+
+// detailView lists the builds running on the selected node and why it is
+// offline
+func (m *NodesModel) detailView() string {
+	n := m.selected()
+	if n == nil {
+		return ""
+	}
+	var s strings.Builder
+	s.WriteString(keywordStyle.Render(n.DisplayName) + subtleStyle.Render(" • "+n.State()))
+	if reason := strings.TrimSpace(n.OfflineCauseReason); reason != "" {
+		s.WriteString(subtleStyle.Render(" • " + reason))
+	}
+	s.WriteString("\n")
+	running := n.Running()
+	if len(running) == 0 {
+		s.WriteString(subtleStyle.Render("  No builds running") + "\n")
+	}
+	for _, e := range running {
+		s.WriteString("  " + e.FullDisplayName + "\n")
+	}
+	return s.String()
+}
+
+func (m *NodesModel) View() string {
+	busy, total := 0, 0
+	for _, n := range m.nodes {
+		busy += n.Busy()
+		total += n.NumExecutors
+	}
+	title := keywordStyle.Render("Nodes") +
+		subtleStyle.Render(fmt.Sprintf(" • %d nodes • %d/%d executors busy • every %s", len(m.nodes), busy, total, m.interval))
+	help := helpStyle.Render("\n enter: logs • x: offline/online • o: open in browser • r: refresh • q: exit\n")
+	return mainStyle.Render("\n"+title+"\n\n"+tableStyle.Render(m.table.View())+"\n"+m.detailView()+m.message) + help
+}
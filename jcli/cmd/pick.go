@@ -37,8 +37,21 @@ type PickModel struct {
 	quitting      bool
 	err           error
 	width, height int
+	// mappings are the jobs of the pipelines in mappingDir
+	mappingDir string
+	mappings   []fileMapping
 }
 
+// fileMapping is the job a pipeline file is mapped to
+type fileMapping struct {
+	name   string
+	job    string
+	mapped bool
+}
+
+// maxMappingLines is the number of mappings listed below the file picker
+const maxMappingLines = 5
+
 type clearErrorMsg struct{}
 
 func clearErrorAfter(t time.Duration) tea.Cmd {
@@ -55,11 +68,14 @@ func (m PickModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width, m.height = msg.Width, msg.Height
+		// Leave room for the mappings below the files
+		m.filepicker.Height = max(msg.Height-maxMappingLines-10, 3)
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "ctrl+c", "q":
-			m.quitting = true
-			return m, tea.Quit
+		case "q":
+			return m, popScreen
+		case "d": // Open the dashboard
+			return m, pushScreen(NewDashboardModel(m.width, m.height))
 		case "enter":
 			var cmd tea.Cmd
 			m.filepicker, cmd = m.filepicker.Update(msg)
@@ -68,6 +84,12 @@ func (m PickModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if didSelect, path := m.filepicker.DidSelectFile(msg); didSelect {
 				// Get the path of the selected file.
 				m.selectedFile = path
+				// Open the build screen for the file, or ask for the job
+				log.Println("\n  You selected: " + m.filepicker.Styles.Selected.Render(m.selectedFile) + "\n")
+				if jobName, ok := mappedJob(m.selectedFile); ok {
+					return m, tea.Batch(cmd, pushScreen(confirmUpdate(NewBuildModel(m.selectedFile, jobName, m.width, m.height))))
+				}
+				return m, tea.Batch(cmd, pushScreen(NewJobPromptModel(m.selectedFile, m.width, m.height)))
 			}
 
 			// Did the user select a disabled file?
@@ -78,25 +100,81 @@ func (m PickModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.selectedFile = ""
 				return m, tea.Batch(cmd, clearErrorAfter(2*time.Second))
 			}
-			// If selected, then switch to build screen
-			selectedFile := m.selectedFile
-			basename := filepath.Base(selectedFile)
-			basename = strings.TrimSuffix(basename, filepath.Ext(basename))
-			log.Println("\n  You selected: " + m.filepicker.Styles.Selected.Render(m.selectedFile) + "\n")
-			log.Println("\n  Banename of file: " + m.filepicker.Styles.Selected.Render(basename) + "\n")
-			newBuildModel := NewBuildModel(selectedFile, m.width, m.height)
-			rootModel := NewMainModel()
-			return rootModel.SwitchScreen(newBuildModel)
+			return m, cmd
 		}
 	case clearErrorMsg:
 		m.err = nil
 	}
 	var cmd tea.Cmd
 	m.filepicker, cmd = m.filepicker.Update(msg)
-
+	if m.filepicker.CurrentDirectory != m.mappingDir {
+		m.updateMappings()
+	}
 	return m, cmd
 }
 
+// updateMappings looks up the jobs of the pipelines in the current directory
+func (m *PickModel) updateMappings() {
+	m.mappingDir = m.filepicker.CurrentDirectory
+	m.mappings = nil
+	entries, err := os.ReadDir(m.mappingDir)
+	if err != nil {
+		log.Println("Error:", err)
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !m.isPipeline(entry.Name()) {
+			continue
+		}
+		job, ok := mappedJob(filepath.Join(m.mappingDir, entry.Name()))
+		m.mappings = append(m.mappings, fileMapping{name: entry.Name(), job: job, mapped: ok})
+	}
+}
+
+// isPipeline reports whether the file has one of the allowed types
+func (m *PickModel) isPipeline(name string) bool {
+	for _, ext := range m.filepicker.AllowedTypes {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// mappingsView lists the jobs of the pipelines in the current directory
+func (m PickModel) mappingsView() string {
+	if len(m.mappings) == 0 {
+		return ""
+	}
+	var s strings.Builder
+	s.WriteString("\n  " + subtleStyle.Render("Jobs of the pipelines in this directory:") + "\n")
+	for i, mapping := range m.mappings {
+		if i == maxMappingLines {
+			s.WriteString(subtleStyle.Render(fmt.Sprintf("  … %d more", len(m.mappings)-i)) + "\n")
+			break
+		}
+		job := keywordStyle.Render(mapping.job)
+		if !mapping.mapped {
+			job = subtleStyle.Render("not mapped")
+		}
+		s.WriteString(fmt.Sprintf("  %s → %s\n", mapping.name, job))
+	}
+	return s.String()
+}
+
+func (m PickModel) Title() string {
+	return "Pick a pipeline"
+}
+
+func (m PickModel) Help() []keyHelp {
+	return []keyHelp{
+		{"enter", "update the job and build the file"},
+		{"h/l", "parent directory/open directory"},
+		{"d", "open the dashboard"},
+		{"q", "quit"},
+	}
+}
+
 func (m PickModel) View() string {
 	if m.quitting {
 		return ""
@@ -111,6 +189,7 @@ func (m PickModel) View() string {
 		s.WriteString("Selected file: " + m.filepicker.Styles.Selected.Render(m.selectedFile))
 	}
 	s.WriteString("\n\n" + m.filepicker.View() + "\n")
+	s.WriteString(m.mappingsView())
 	return s.String()
 }
 
@@ -120,6 +199,7 @@ func NewPickModel() PickModel {
 	fp.CurrentDirectory, _ = os.Getwd()
 	fp.ShowPermissions = false
 	fp.ShowSize = true
+	fp.AutoHeight = false
 
 	return PickModel{
 		filepicker: fp,
@@ -136,10 +216,7 @@ func pickmain() {
 	defer f.Close()
 
 	m := NewPickModel()
-	tm, _ := tea.NewProgram(&m).Run()
-	mm := tm.(PickModel)
-	log.Println("\n  You selected: " + m.filepicker.Styles.Selected.Render(mm.selectedFile) + "\n")
-	relativePath := strings.Replace(mm.selectedFile, mm.filepicker.CurrentDirectory+"/", "", 1)
-	log.Println("\n  Relative path: " + m.filepicker.Styles.Selected.Render(relativePath) + "\n")
-
+	if _, err := tea.NewProgram(NewMainModel(&m), tea.WithAltScreen()).Run(); err != nil {
+		fmt.Println("could not start program:", err)
+	}
 }
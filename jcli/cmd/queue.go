@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"jcli/jenkins"
+
+	"github.com/spf13/cobra"
+)
+
+// queueCmd represents the queue command
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "List the items waiting in the build queue",
+	Long: `List the items in the build queue in the order Jenkins will start them,
+with how long they have been waiting, the label they wait for and the reason
+Jenkins gives.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		items, err := Jenkins.GetQueue()
+		if err != nil {
+			log.Fatal("Error: Could not get build queue: ", err)
+		}
+		if len(items) == 0 {
+			fmt.Println("The build queue is empty")
+			return
+		}
+		printQueue(items)
+	},
+}
+
+var queueCancelCmd = &cobra.Command{
+	Use:   "cancel <id>...",
+	Short: "Remove items from the build queue",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed := false
+		for _, arg := range args {
+			id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+			if err != nil {
+				fmt.Println("Error: Invalid queue item id", arg)
+				failed = true
+				continue
+			}
+			if err := Jenkins.CancelQueueItem(id); err != nil {
+				fmt.Println("Error: Could not cancel queue item", id, err)
+				failed = true
+				continue
+			}
+			fmt.Println("Cancelled queue item", id)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(queueCmd)
+	queueCmd.AddCommand(queueCancelCmd)
+}
+
+var queueColumns = []string{"ID", "JOB", "WAITING", "STATE", "LABEL", "REASON"}
+
+// queueState summarizes the flags of a queue item
+func queueState(item jenkins.QueueItem) string {
+	switch {
+	case item.Stuck:
+		return "stuck"
+	case item.Blocked:
+		return "blocked"
+	case item.Buildable:
+		return "buildable"
+	}
+	return "waiting"
+}
+
+func printQueue(items []jenkins.QueueItem) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(queueColumns, "\t"))
+	for _, item := range items {
+		label := item.Label()
+		if label == "" {
+			label = "-"
+		}
+		// Reasons can span several lines, like the list of blocking builds
+		why := strings.Join(strings.Fields(item.Why), " ")
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			item.Id, item.Task.Name, item.Waiting(), queueState(item), label, why)
+	}
+	w.Flush()
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"jcli/jenkins"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+// replayCmd represents the replay command
+var replayCmd = &cobra.Command{
+	Use:   "replay <job> [build]",
+	Short: "Replay a build with a local pipeline script and shared libraries",
+	Long: `Run a build of a job again, by default the last one, with the pipeline
+script in --script and the sources of local shared library checkouts given
+with --lib name=path. The vars/ and src/ scripts of a library replace the ones
+the build loaded. Scripts the build did not load cannot be replaced, so build
+once with the library from its repository first.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		jobName, ref := args[0], ""
+		if len(args) > 1 {
+			ref = args[1]
+		}
+		buildUrl, err := Jenkins.ResolveBuildUrl(jobName, ref)
+		if err != nil {
+			log.Fatal("Error: Could not find build ", Jenkins.BuildUrl(jobName, ref), ": ", err)
+		}
+		script := ""
+		if replayScript != "" {
+			if script, err = jenkins.LoadPipelineScriptFromFile(filepath.Clean(replayScript)); err != nil {
+				log.Fatal("Error: Could not read pipeline script from file ", replayScript, ": ", err)
+			}
+		}
+		newUrl, skipped, err := replayBuild(jobName, buildUrl, script, replayLibs)
+		if err != nil {
+			log.Fatal("Error: Could not replay ", buildUrl, ": ", err)
+		}
+		for _, name := range skipped {
+			fmt.Println("Warning:", name, "was not loaded by the build and was not replaced")
+		}
+		fmt.Println("Started", newUrl)
+		if !replayFollow {
+			return
+		}
+
+		f, err := tea.LogToFile("lazyjenkins.log", "replay")
+		if err != nil {
+			fmt.Println("fatal:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		m := NewBuildModelFromUrl(jobName, newUrl, 80, 24)
+		p := tea.NewProgram(NewMainModel(m), tea.WithMouseCellMotion(), tea.WithAltScreen())
+		if _, err := p.Run(); err != nil {
+			fmt.Println("could not start program:", err)
+		}
+	},
+}
+
+var (
+	replayScript string
+	replayLibs   map[string]string
+	replayFollow bool
+)
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().StringVarP(&replayScript, "script", "s", "", "Pipeline script to run instead of the one of the build.")
+	replayCmd.Flags().StringToStringVar(&replayLibs, "lib", nil, "Local checkout of a shared library as name=path. Can be repeated.")
+	replayCmd.Flags().BoolVarP(&replayFollow, "follow", "f", false, "Follow the log of the new build.")
+}
+
+// libraryScripts reads the scripts of local shared library checkouts, keyed
+// by the names Jenkins loads them as: vars/deploy.groovy is "deploy" and
+// src/com/example/Utils.groovy is "com.example.Utils"
+func libraryScripts(libs map[string]string) (map[string]string, error) {
+	scripts := map[string]string{}
+	for name, dir := range libs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("library %s: %s is not a directory", name, dir)
+		}
+		vars, err := filepath.Glob(filepath.Join(dir, "vars", "*.groovy"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range vars {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			scripts[strings.TrimSuffix(filepath.Base(file), ".groovy")] = string(data)
+		}
+
+		src := filepath.Join(dir, "src")
+		err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || filepath.Ext(path) != ".groovy" {
+				return err
+			}
+			rel, err := filepath.Rel(src, path)
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			class := strings.TrimSuffix(filepath.ToSlash(rel), ".groovy")
+			scripts[strings.ReplaceAll(class, "/", ".")] = string(data)
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return scripts, nil
+}
+
+// replayBuild replays the build with the main script, unless it is empty,
+// and the scripts of the local libraries. It returns the URL of the new
+// build and the library scripts which could not be replaced.
+func replayBuild(jobName, buildUrl, script string, libs map[string]string) (string, []string, error) {
+	form, err := Jenkins.GetReplayForm(buildUrl)
+	if err != nil {
+		return "", nil, err
+	}
+	if script != "" {
+		form.MainScript = script
+	}
+	scripts, err := libraryScripts(libs)
+	if err != nil {
+		return "", nil, err
+	}
+	var skipped []string
+	for name, content := range scripts {
+		field := jenkins.ReplayField(name)
+		if _, ok := form.LoadedScripts[field]; !ok {
+			skipped = append(skipped, name)
+			continue
+		}
+		form.LoadedScripts[field] = content
+	}
+	sort.Strings(skipped)
+
+	original, err := Jenkins.GetBuild(buildUrl)
+	if err != nil {
+		return "", nil, err
+	}
+	next, err := Jenkins.NextBuildNumber(jobName)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := Jenkins.Replay(buildUrl, form); err != nil {
+		return "", nil, err
+	}
+	newUrl, err := Jenkins.WaitForReplay(jobName, original.Number, next, Config.Watch.Timeout)
+	return newUrl, skipped, err
+}
+
+// replayWithLibraries builds the job by replaying its last build with the
+// local libraries of the model. It returns the URL of the new build and the
+// library scripts which could not be replaced. Jobs without builds have no
+// loaded scripts to replace, so they are not built.
+func (m *BuildModel) replayWithLibraries(script string) (string, []string, error) {
+	lastBuild, err := Jenkins.ResolveBuildUrl(m.JobName, "lastBuild")
+	if err != nil {
+		return "", nil, fmt.Errorf("no build to replay with the local libraries, build once without --lib first: %w", err)
+	}
+	buildUrl, skipped, err := replayBuild(m.JobName, lastBuild, script, m.libs)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not replay %s: %w", lastBuild, err)
+	}
+	return buildUrl, skipped, nil
+}
@@ -4,10 +4,13 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"log"
 	"os"
 
 	"jcli/auth"
+	"jcli/config"
 	"jcli/jenkins"
+	"jcli/jenkins/cassette"
 
 	"github.com/spf13/cobra"
 )
@@ -15,12 +18,20 @@ import (
 var Address string
 var User string
 var Jenkins *jenkins.Jenkins
+var cfgFile string
+var recordFile string
+var Config *config.Config
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "jcli",
 	Short: "A brief description of your application",
 	Long:  `Long description of your application. This is where you would put`,
+	// Every command talks to Jenkins with the settings of the config file
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		InitConfig()
+		InitJenkins()
+	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 }
@@ -37,11 +48,44 @@ func Execute() {
 func InitJenkins() {
 	apiKey := auth.LoadAPIKeyfromKeyring(Address, User)
 	Jenkins = jenkins.NewJenkins(Address, User, apiKey)
+	Jenkins.OnRetry = notifyRetry
+	if recordFile != "" {
+		recorder, err := cassette.New(recordFile, cassette.Record)
+		if err != nil {
+			log.Fatal("Error: Could not record to ", recordFile, ": ", err)
+		}
+		recorder.Wrap(Jenkins)
+	}
+}
+
+// InitConfig loads the config file, falling back to the defaults if it does not exist
+func InitConfig() {
+	if cfgFile == "" {
+		cfgFile = config.DefaultPath()
+	}
+	var err error
+	Config, err = config.Load(cfgFile)
+	if err != nil {
+		log.Fatal("Error: Could not read config file ", cfgFile, ": ", err)
+	}
+	// Settings of the project in the working directory take precedence
+	if project := config.FindProjectFile("."); project != "" && !sameFile(project, cfgFile) {
+		if err := Config.LoadProject(project); err != nil {
+			log.Fatal("Error: Could not read project config file ", project, ": ", err)
+		}
+	}
+}
+
+// sameFile reports whether both paths point to the same existing file
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
 }
 
 func init() {
 	// Always init Jenkins before running any command
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.jcli.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.jcli.yaml)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -49,4 +93,7 @@ func init() {
 	rootCmd.MarkFlagRequired("address")
 	rootCmd.PersistentFlags().StringVarP(&User, "user", "u", "", "User to connect to Jenkins server.")
 	rootCmd.MarkFlagRequired("user")
+	// Records the requests of a command as a cassette for regression tests
+	rootCmd.PersistentFlags().StringVar(&recordFile, "record", "", "Record the requests to the server to a cassette file.")
+	rootCmd.PersistentFlags().MarkHidden("record")
 }
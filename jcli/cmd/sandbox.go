@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"jcli/config"
+	"jcli/jenkins"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// sandboxCmd represents the sandbox command
+var sandboxCmd = &cobra.Command{
+	Use:   "sandbox",
+	Short: "Manage the sandbox jobs of your git branches",
+	Long: `Sandboxes are jobs of a developer and git branch, which update --sandbox
+creates on first use. Their names come from the job template in the sandbox
+section of the config file, which defaults to
+` + config.DefaultSandboxJob + `.`,
+}
+
+var sandboxPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete the sandboxes of merged and deleted branches",
+	Long: `Delete your sandbox jobs of branches which are merged into the base branch
+of the sandbox config, or which do not exist locally or on a remote anymore.
+Only jobs matching the sandbox job template are deleted, and sandboxes of
+deleted branches only if the template contains the project. The sandboxes are
+listed and the deletion confirmed first, unless --yes is given.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pruneSandboxes(os.Stdout, sandboxDryRun); err != nil {
+			log.Fatal("Error: ", err)
+		}
+	},
+}
+
+var (
+	sandboxDryRun bool
+	sandboxYes    bool
+)
+
+func init() {
+	rootCmd.AddCommand(sandboxCmd)
+	sandboxCmd.AddCommand(sandboxPruneCmd)
+	sandboxPruneCmd.Flags().BoolVarP(&sandboxDryRun, "dry-run", "n", false, "Only list the sandboxes which would be deleted.")
+	sandboxPruneCmd.Flags().BoolVarP(&sandboxYes, "yes", "y", false, "Delete the sandboxes without asking for confirmation.")
+}
+
+// sandboxJob returns the sandbox of the pipeline file for the current branch
+// and the job new sandboxes are copied from
+func sandboxJob(file string) (string, string, error) {
+	vars := jobVars(file)
+	if vars.Branch == "" || vars.Branch == "HEAD" {
+		return "", "", fmt.Errorf("sandboxes need a git branch, but %s is not on one", file)
+	}
+	jobName, err := config.ExpandJob(Config.Sandbox.Job, vars)
+	if err != nil {
+		return "", "", err
+	}
+	templateJob := Config.Sandbox.Template
+	if templateJob == "" {
+		templateJob, _ = mappedJob(file)
+	}
+	return jobName, templateJob, nil
+}
+
+// createJobFrom creates the job and its folders with the configuration of
+// the template job, or as an empty pipeline if the template does not exist
+func createJobFrom(jobName, templateJob string) error {
+	if err := Jenkins.CreateFolders(jenkins.ParentFolder(jobName)); err != nil {
+		return err
+	}
+	jobConfig := jenkins.PipelineJobConfig
+	exists, err := Jenkins.JobExists(templateJob)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if jobConfig, err = Jenkins.GetJobConfig(templateJob); err != nil {
+			return err
+		}
+	} else {
+		log.Println("Template job", templateJob, "does not exist, creating an empty pipeline")
+	}
+	return Jenkins.CreateJob(jobName, jobConfig)
+}
+
+// sandboxPattern returns the folder of the sandboxes and a pattern matching
+// the names of the sandboxes of all branches
+func sandboxPattern(vars config.JobVars) (string, *regexp.Regexp, error) {
+	// NUL is no valid character of a branch name
+	vars.Branch = "\x00"
+	placeholder, err := config.ExpandJob(Config.Sandbox.Job, vars)
+	if err != nil {
+		return "", nil, err
+	}
+	folder := jenkins.ParentFolder(placeholder)
+	if folder == "" || strings.Contains(folder, "\x00") {
+		return "", nil, fmt.Errorf("the sandbox job template %q has to put the sandboxes into a folder, with the branch only in the job name", Config.Sandbox.Job)
+	}
+	parts := strings.Split(placeholder, "\x00")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return folder, regexp.MustCompile("^" + strings.Join(parts, "[^/]+") + "$"), nil
+}
+
+// sandboxHasProject reports whether the sandbox job template tells apart
+// the sandboxes of different projects
+func sandboxHasProject(vars config.JobVars) bool {
+	a, errA := config.ExpandJob(Config.Sandbox.Job, vars)
+	vars.Project += "\x00"
+	b, errB := config.ExpandJob(Config.Sandbox.Job, vars)
+	return errA == nil && errB == nil && a != b
+}
+
+// pruneSandboxes deletes the sandboxes of the user whose branches are merged
+// or gone. Sandboxes of deleted branches are only pruned if the job template
+// contains the project, otherwise they could belong to another project.
+func pruneSandboxes(w io.Writer, dryRun bool) error {
+	dir := Config.ProjectDir()
+	vars := jobVars(filepath.Join(dir, config.ProjectFileName))
+	branches, err := gitBranches(dir)
+	if err != nil {
+		return fmt.Errorf("could not list the branches of %s: %w", dir, err)
+	}
+	merged, err := gitMergedBranches(dir, Config.Sandbox.Base)
+	if err != nil {
+		return fmt.Errorf("could not list the branches merged into %s: %w", Config.Sandbox.Base, err)
+	}
+	isMerged := map[string]bool{}
+	for _, branch := range merged {
+		isMerged[branch] = true
+	}
+
+	// The template puts the branch into the job name, so the folder is the
+	// same for all branches
+	folder, pattern, err := sandboxPattern(vars)
+	if err != nil {
+		return err
+	}
+	ownProject := sandboxHasProject(vars)
+
+	// Map the sandbox names back to their branches
+	owners := map[string]string{}
+	for _, branch := range branches {
+		vars.Branch = branch
+		jobName, err := config.ExpandJob(Config.Sandbox.Job, vars)
+		if err != nil {
+			return err
+		}
+		owners[jobName] = branch
+	}
+
+	items, err := Jenkins.ListItems(folder)
+	if err != nil {
+		return fmt.Errorf("could not list the sandboxes in %s: %w", folder, err)
+	}
+	type sandbox struct{ job, reason string }
+	var pruned []sandbox
+	unknown := 0
+	for _, item := range items {
+		if item.IsFolder() || !pattern.MatchString(item.FullName) {
+			continue
+		}
+		branch, ok := owners[item.FullName]
+		switch {
+		case !ok && !ownProject:
+			unknown++
+		case !ok:
+			pruned = append(pruned, sandbox{item.FullName, "branch is gone"})
+		case isMerged[branch] && branch != Config.Sandbox.Base:
+			pruned = append(pruned, sandbox{item.FullName, "merged into " + Config.Sandbox.Base})
+		}
+	}
+	if unknown > 0 {
+		fmt.Fprintf(w, "Keeping %d sandboxes of other branches in %s, the sandbox job template does not contain the project\n", unknown, folder)
+	}
+	if len(pruned) == 0 {
+		fmt.Fprintln(w, "No sandboxes to prune in", folder)
+		return nil
+	}
+	for _, s := range pruned {
+		fmt.Fprintf(w, "Would delete %s (%s)\n", s.job, s.reason)
+	}
+	if dryRun || !confirmAction(fmt.Sprintf("Delete %d sandboxes with all their builds?", len(pruned)), sandboxYes) {
+		return nil
+	}
+	for _, s := range pruned {
+		if err := Jenkins.DeleteJob(s.job); err != nil {
+			return fmt.Errorf("could not delete %s: %w", s.job, err)
+		}
+		fmt.Fprintf(w, "Deleted %s (%s)\n", s.job, s.reason)
+	}
+	return nil
+}
+
+// confirmAction asks the question on the terminal unless yes is set by
+// --yes. Without a terminal, only --yes confirms.
+func confirmAction(question string, yes bool) bool {
+	if yes {
+		return true
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		log.Fatal("Error: ", question, " Confirm with --yes when not running in a terminal")
+	}
+	r := bufio.NewReader(os.Stdin)
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	answer, err := r.ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
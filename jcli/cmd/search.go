@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	util "jcli/jenkins"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	// sgrRegexp matches the color sequences left in the log by SanitizeANSI
+	sgrRegexp         = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	matchStyle        = lipgloss.NewStyle().Background(lipgloss.Color("58")).Foreground(lipgloss.Color("230"))
+	currentMatchStyle = lipgloss.NewStyle().Background(lipgloss.Color("214")).Foreground(lipgloss.Color("16"))
+)
+
+// logSearch holds the state of a search in the build log
+type logSearch struct {
+	input   textinput.Model
+	editing bool
+	regexp  *regexp.Regexp
+	// matches are the indices of the log lines which match
+	matches []int
+	current int
+	// lastError is the index of the log line of the last error jumped to
+	lastError int
+}
+
+func newLogSearch() logSearch {
+	ti := textinput.New()
+	ti.Prompt = "/"
+	ti.Placeholder = "regex"
+	return logSearch{input: ti, current: -1, lastError: -1}
+}
+
+// active reports whether search results are highlighted
+func (s *logSearch) active() bool {
+	return s.regexp != nil
+}
+
+// compileSearch compiles the query as a regular expression. Queries which
+// are not valid yet, like an unclosed group while typing, match literally.
+func compileSearch(query string) *regexp.Regexp {
+	if query == "" {
+		return nil
+	}
+	re, err := regexp.Compile(query)
+	if err != nil {
+		re = regexp.MustCompile(regexp.QuoteMeta(query))
+	}
+	return re
+}
+
+// compileErrorPatterns combines the configured error patterns into one regexp
+func compileErrorPatterns(patterns []string) *regexp.Regexp {
+	var valid []string
+	for _, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			log.Println("Error: Ignoring invalid error pattern", p, err)
+			continue
+		}
+		valid = append(valid, "(?:"+p+")")
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(strings.Join(valid, "|"))
+}
+
+// setLogContent sets the log shown in the viewport, keeping search
+// highlights up to date
+func (m *BuildModel) setLogContent(content string) {
+	m.logContent = content
+	m.renderLog()
+}
+
+// renderLog writes the log into the viewport and highlights search matches
+func (m *BuildModel) renderLog() {
+	if !m.search.active() {
+		m.search.matches = nil
+		m.viewport.SetContent(m.logContent)
+		return
+	}
+	lines := strings.Split(m.logContent, "\n")
+	m.search.matches = m.search.matches[:0]
+	for i, line := range lines {
+		plain := util.StripANSI(line)
+		if !m.search.regexp.MatchString(plain) {
+			continue
+		}
+		style := matchStyle
+		if len(m.search.matches) == m.search.current {
+			style = currentMatchStyle
+		}
+		m.search.matches = append(m.search.matches, i)
+		lines[i] = highlightMatches(line, m.search.regexp, style)
+	}
+	if m.search.current >= len(m.search.matches) {
+		m.search.current = len(m.search.matches) - 1
+	}
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// highlightMatches highlights the matches of the regexp in a line with
+// colors. The colors of the line are restored after every match.
+func highlightMatches(line string, re *regexp.Regexp, style lipgloss.Style) string {
+	// offsets are the positions of the characters of the plain text in the
+	// line, which are the characters between the color sequences
+	var plain strings.Builder
+	offsets := make([]int, 0, len(line))
+	pos := 0
+	for _, seq := range append(sgrRegexp.FindAllStringIndex(line, -1), []int{len(line), len(line)}) {
+		for k := pos; k < seq[0]; k++ {
+			offsets = append(offsets, k)
+		}
+		plain.WriteString(line[pos:seq[0]])
+		pos = seq[1]
+	}
+
+	text := plain.String()
+	var out strings.Builder
+	pos = 0
+	for _, match := range re.FindAllStringIndex(text, -1) {
+		if match[0] == match[1] {
+			continue
+		}
+		start, end := offsets[match[0]], offsets[match[1]-1]+1
+		out.WriteString(line[pos:start])
+		out.WriteString(style.Render(text[match[0]:match[1]]))
+		// The style resets all colors, so the sequences up to the end of the
+		// match are repeated
+		for _, seq := range sgrRegexp.FindAllString(line[:end], -1) {
+			out.WriteString(seq)
+		}
+		pos = end
+	}
+	out.WriteString(line[pos:])
+	return out.String()
+}
+
+// startSearch opens the search prompt and pauses auto-scrolling
+func (m *BuildModel) startSearch() tea.Cmd {
+	m.userScrolled = true
+	m.search.editing = true
+	m.search.input.SetValue("")
+	return m.search.input.Focus()
+}
+
+// clearSearch removes the search and its highlights
+func (m *BuildModel) clearSearch() {
+	m.search.editing = false
+	m.search.input.Blur()
+	m.search.regexp = nil
+	m.search.current = -1
+	m.renderLog()
+}
+
+// updateSearch handles keys while the search prompt is open
+func (m *BuildModel) updateSearch(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.clearSearch()
+		return nil
+	case "enter":
+		m.search.editing = false
+		m.search.input.Blur()
+		return nil
+	}
+	var cmd tea.Cmd
+	m.search.input, cmd = m.search.input.Update(msg)
+	// Search incrementally from the current position while typing
+	m.search.regexp = compileSearch(m.search.input.Value())
+	m.search.current = 0
+	m.renderLog()
+	for i, line := range m.search.matches {
+		if line >= m.viewport.YOffset {
+			m.search.current = i
+			break
+		}
+	}
+	m.jumpToMatch(0)
+	return cmd
+}
+
+// jumpToMatch moves the current match by delta and scrolls to it
+func (m *BuildModel) jumpToMatch(delta int) {
+	if len(m.search.matches) == 0 {
+		return
+	}
+	n := len(m.search.matches)
+	m.search.current = ((m.search.current+delta)%n + n) % n
+	m.renderLog()
+	m.scrollToLine(m.search.matches[m.search.current])
+}
+
+// jumpToError scrolls to the next line, or the previous one if backwards
+// is set, which matches one of the configured error patterns. The search
+// starts after the last error jumped to and wraps around the log.
+func (m *BuildModel) jumpToError(backwards bool) {
+	if m.errorRegexp == nil {
+		return
+	}
+	m.userScrolled = true
+	lines := strings.Split(m.logContent, "\n")
+	n := len(lines)
+	step := 1
+	if backwards {
+		step = -1
+	}
+	current := m.search.lastError
+	if current < 0 || current >= n {
+		// Start with the first line shown, or the last one backwards
+		current = m.viewport.YOffset - 1
+		if backwards {
+			current = m.viewport.YOffset + m.viewport.Height
+		}
+	}
+	for k := 1; k <= n; k++ {
+		i := ((current+k*step)%n + n) % n
+		if m.errorRegexp.MatchString(util.StripANSI(lines[i])) {
+			m.search.lastError = i
+			m.scrollToLine(i)
+			return
+		}
+	}
+}
+
+// scrollToLine scrolls the viewport so that the line is in the middle
+func (m *BuildModel) scrollToLine(line int) {
+	offset := line - m.viewport.Height/2
+	if offset < 0 {
+		offset = 0
+	}
+	m.viewport.SetYOffset(offset)
+}
+
+// searchView renders the search prompt or the position in the results
+func (m *BuildModel) searchView() string {
+	if m.search.editing {
+		return m.search.input.View() + subtleStyle.Render(fmt.Sprintf("  %d matches", len(m.search.matches)))
+	}
+	if m.search.active() {
+		return subtleStyle.Render(fmt.Sprintf("/%s  %d/%d • n/N: next/previous • esc: clear",
+			m.search.regexp.String(), m.search.current+1, len(m.search.matches)))
+	}
+	return ""
+}
@@ -0,0 +1,417 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"jcli/jenkins"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync <dir>",
+	Short: "Create and update the jobs of a directory of pipeline scripts",
+	Long: `Sync a directory of pipeline scripts to pipeline jobs. The folders of the
+jobs mirror the directories: a/b/Jenkinsfile is the job a/b, and
+a/b/deploy.groovy or a/b/deploy.Jenkinsfile is the job a/b/deploy. A
+Jenkinsfile at the top is named after the directory. With --folder, the jobs
+are created inside that folder.
+
+Missing folders and jobs are created and jobs with a different script are
+updated. With --prune, pipeline jobs in the folder without a script are
+deleted, and so are the subfolders which are left empty. Pruning needs
+--folder, so that jobs elsewhere on the server are never deleted. The plan is
+printed first, --dry-run stops after it. Deletions are confirmed before the
+plan is applied, unless --yes is given.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		items, err := planSync(args[0], syncFolder, syncPrune, syncConcurrency)
+		if err != nil {
+			log.Fatal("Error: ", err)
+		}
+		printSyncPlan(os.Stdout, items)
+		if syncDryRun {
+			return
+		}
+		if deletes := countSyncDeletes(items); deletes > 0 &&
+			!confirmAction(fmt.Sprintf("Delete %d jobs and folders with all their builds?", deletes), syncYes) {
+			fmt.Println("Nothing was changed")
+			return
+		}
+		if failed := applySync(os.Stdout, items, syncConcurrency); failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+var (
+	syncDryRun      bool
+	syncPrune       bool
+	syncFolder      string
+	syncConcurrency int
+	syncYes         bool
+)
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().BoolVarP(&syncDryRun, "dry-run", "n", false, "Only print the plan without changing any job.")
+	syncCmd.Flags().BoolVar(&syncPrune, "prune", false, "Delete the pipeline jobs in the folder which have no script and the folders left empty.")
+	syncCmd.Flags().StringVarP(&syncFolder, "folder", "f", "", "Folder to sync the jobs into instead of the top level.")
+	syncCmd.Flags().IntVarP(&syncConcurrency, "concurrency", "c", 4, "How many jobs are synced at the same time.")
+	syncCmd.Flags().BoolVarP(&syncYes, "yes", "y", false, "Delete pruned jobs without asking for confirmation.")
+}
+
+type syncAction int
+
+const (
+	syncUnchanged syncAction = iota
+	syncCreate
+	syncUpdate
+	syncDelete
+)
+
+// syncItem is the planned change of a job
+type syncItem struct {
+	action syncAction
+	job    string
+	file   string
+	script string
+	// folder is set for the deletion of a folder left empty by pruning
+	folder bool
+	// err is set if the job cannot be synced
+	err error
+}
+
+// syncSymbols mark the actions in the plan
+var syncSymbols = map[syncAction]string{syncCreate: "+", syncUpdate: "~", syncDelete: "-"}
+
+// syncJobName returns the job of a pipeline script in the synced directory,
+// or an empty string if the file is no pipeline script
+func syncJobName(dir, file string) string {
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		return ""
+	}
+	rel = filepath.ToSlash(rel)
+	base := path.Base(rel)
+	var name string
+	switch {
+	case base == "Jenkinsfile":
+		name = path.Dir(rel)
+		if name == "." {
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				return ""
+			}
+			name = filepath.Base(abs)
+		}
+		return name
+	case strings.HasSuffix(base, ".Jenkinsfile"):
+		name = strings.TrimSuffix(base, ".Jenkinsfile")
+	case strings.HasSuffix(base, ".groovy"):
+		name = strings.TrimSuffix(base, ".groovy")
+	default:
+		return ""
+	}
+	return path.Join(path.Dir(rel), name)
+}
+
+// pipelineScripts finds the pipeline scripts in the directory by job
+func pipelineScripts(dir, folder string) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if file != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := syncJobName(dir, file)
+		if name == "" {
+			return nil
+		}
+		job := path.Join(strings.Trim(folder, "/"), name)
+		if other, ok := files[job]; ok {
+			return fmt.Errorf("%s and %s are both scripts of job %s", other, file, job)
+		}
+		files[job] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// A job cannot be the folder of other jobs at the same time
+	for job, file := range files {
+		for parent := jenkins.ParentFolder(job); parent != ""; parent = jenkins.ParentFolder(parent) {
+			if other, ok := files[parent]; ok {
+				return nil, fmt.Errorf("%s is the script of job %s, which is the folder of %s", other, parent, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// planSync compares the scripts in the directory with the jobs on the server
+func planSync(dir, folder string, prune bool, concurrency int) ([]syncItem, error) {
+	if prune && strings.Trim(folder, "/") == "" {
+		return nil, fmt.Errorf("--prune needs --folder, it would delete the jobs of the whole server otherwise")
+	}
+	files, err := pipelineScripts(dir, folder)
+	if err != nil {
+		return nil, err
+	}
+	var items []syncItem
+	for job, file := range files {
+		items = append(items, syncItem{job: job, file: file})
+	}
+	// Failures are kept in the items, so the plan shows all of them
+	var g errgroup.Group
+	g.SetLimit(max(concurrency, 1))
+	for i := range items {
+		item := &items[i]
+		g.Go(func() error {
+			item.script, item.err = jenkins.LoadPipelineScriptFromFile(item.file)
+			if item.err != nil {
+				return nil
+			}
+			exists, err := Jenkins.JobExists(item.job)
+			if err != nil || !exists {
+				item.action, item.err = syncCreate, err
+				return nil
+			}
+			config, err := Jenkins.GetJobConfig(item.job)
+			if err != nil {
+				item.err = err
+				return nil
+			}
+			current, err := jenkins.ExtractPipelineScript(config)
+			if err != nil {
+				item.err = err
+				return nil
+			}
+			if current != item.script {
+				item.action = syncUpdate
+			}
+			return nil
+		})
+	}
+	g.Wait()
+
+	if prune {
+		orphans, err := orphanedJobs(strings.Trim(folder, "/"), files)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, orphans...)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].job < items[j].job })
+	return items, nil
+}
+
+// orphanedJobs returns the deletions of the pipeline jobs in the folder and
+// its subfolders which have no script, and of the subfolders which are empty
+// once these are deleted. Other kinds of jobs, like multibranch projects, are
+// never pruned, and neither are the folders containing them.
+func orphanedJobs(folder string, files map[string]string) ([]syncItem, error) {
+	if folder != "" {
+		exists, err := Jenkins.JobExists(folder)
+		if err != nil || !exists {
+			return nil, err
+		}
+	}
+	orphans, _, err := orphansIn(folder, files)
+	return orphans, err
+}
+
+// orphansIn returns the orphaned jobs and subfolders of the folder and
+// whether it is empty once they are deleted
+func orphansIn(folder string, files map[string]string) ([]syncItem, bool, error) {
+	items, err := Jenkins.ListItems(folder)
+	if err != nil {
+		return nil, false, err
+	}
+	var orphans []syncItem
+	empty := true
+	for _, item := range items {
+		switch item.Class {
+		case "com.cloudbees.hudson.plugins.folder.Folder":
+			children, childEmpty, err := orphansIn(item.FullName, files)
+			if err != nil {
+				return nil, false, err
+			}
+			orphans = append(orphans, children...)
+			if childEmpty && !hasScripts(files, item.FullName) {
+				orphans = append(orphans, syncItem{action: syncDelete, job: item.FullName, folder: true})
+			} else {
+				empty = false
+			}
+		case "org.jenkinsci.plugins.workflow.job.WorkflowJob":
+			if _, ok := files[item.FullName]; ok {
+				empty = false
+			} else {
+				orphans = append(orphans, syncItem{action: syncDelete, job: item.FullName})
+			}
+		default:
+			empty = false
+		}
+	}
+	return orphans, empty, nil
+}
+
+// hasScripts reports whether any script is synced to the folder or into it
+func hasScripts(files map[string]string, folder string) bool {
+	for job := range files {
+		if job == folder || strings.HasPrefix(job, folder+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteEmptyFolder deletes the folder, unless a job in it could not be
+// deleted, which would be deleted with the folder otherwise
+func deleteEmptyFolder(folder string) error {
+	items, err := Jenkins.ListItems(folder)
+	if err != nil {
+		return err
+	}
+	if len(items) > 0 {
+		return fmt.Errorf("the folder still contains %s", items[0].FullName)
+	}
+	return Jenkins.DeleteJob(folder)
+}
+
+// countSyncDeletes returns how many jobs the plan deletes
+func countSyncDeletes(items []syncItem) int {
+	deletes := 0
+	for _, item := range items {
+		if item.action == syncDelete && item.err == nil {
+			deletes++
+		}
+	}
+	return deletes
+}
+
+// printSyncPlan lists the changes and failures of the plan with a summary
+func printSyncPlan(w io.Writer, items []syncItem) {
+	counts := map[syncAction]int{}
+	failed := 0
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, item := range items {
+		if item.err != nil {
+			failed++
+			fmt.Fprintf(tw, "!\t%s\t%s: %s\n", item.job, item.file, item.err)
+			continue
+		}
+		counts[item.action]++
+		switch {
+		case item.folder:
+			fmt.Fprintf(tw, "%s\t%s/\t(empty folder)\n", syncSymbols[item.action], item.job)
+		case item.action != syncUnchanged:
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", syncSymbols[item.action], item.job, item.file)
+		}
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "Plan: %d to create, %d to update, %d to delete, %d unchanged",
+		counts[syncCreate], counts[syncUpdate], counts[syncDelete], counts[syncUnchanged])
+	if failed > 0 {
+		fmt.Fprintf(w, ", %d cannot be synced", failed)
+	}
+	fmt.Fprintln(w)
+}
+
+// applySync carries out the plan and returns how many changes failed
+func applySync(w io.Writer, items []syncItem, concurrency int) int {
+	var mu sync.Mutex
+	counts := map[syncAction]int{}
+	failed := 0
+	report := func(item syncItem, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			failed++
+			fmt.Fprintf(w, "Error: Could not sync %s: %s\n", item.job, err)
+			return
+		}
+		counts[item.action]++
+	}
+
+	// Create the folders first, jobs in the same folder would race for them
+	folders := map[string]bool{}
+	for _, item := range items {
+		if item.action == syncCreate && item.err == nil {
+			folders[jenkins.ParentFolder(item.job)] = true
+		}
+	}
+	failedFolders := map[string]error{}
+	for folder := range folders {
+		if err := Jenkins.CreateFolders(folder); err != nil {
+			failedFolders[folder] = err
+		}
+	}
+
+	var g errgroup.Group
+	g.SetLimit(max(concurrency, 1))
+	var emptyFolders []syncItem
+	for _, item := range items {
+		if item.err != nil || item.action == syncUnchanged {
+			continue
+		}
+		if item.folder {
+			// Folders are deleted once the jobs in them are gone
+			emptyFolders = append(emptyFolders, item)
+			continue
+		}
+		g.Go(func() error {
+			var err error
+			switch item.action {
+			case syncCreate:
+				if err = failedFolders[jenkins.ParentFolder(item.job)]; err != nil {
+					break
+				}
+				var config string
+				if config, err = jenkins.ReplacePipelineScript(jenkins.PipelineJobConfig, item.script); err == nil {
+					err = Jenkins.CreateJob(item.job, config)
+				}
+			case syncUpdate:
+				var config string
+				if config, err = Jenkins.GetJobConfig(item.job); err != nil {
+					break
+				}
+				if config, err = jenkins.ReplacePipelineScript(config, item.script); err == nil {
+					err = Jenkins.UpdateJobConfig(item.job, config)
+				}
+			case syncDelete:
+				err = Jenkins.DeleteJob(item.job)
+			}
+			report(item, err)
+			return nil
+		})
+	}
+	g.Wait()
+	// Subfolders sort after their parents, so they are deleted first
+	for i := len(emptyFolders) - 1; i >= 0; i-- {
+		report(emptyFolders[i], deleteEmptyFolder(emptyFolders[i].job))
+	}
+	fmt.Fprintf(w, "Applied: %d created, %d updated, %d deleted", counts[syncCreate], counts[syncUpdate], counts[syncDelete])
+	if failed > 0 {
+		fmt.Fprintf(w, ", %d failed", failed)
+	}
+	fmt.Fprintln(w)
+	return failed
+}
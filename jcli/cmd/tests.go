@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"jcli/jenkins"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+var testsJUnitOut string
+
+var (
+	passStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	failStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	skipStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+)
+
+// testsCmd represents the tests command
+var testsCmd = &cobra.Command{
+	Use:   "tests <job> [build|lastBuild]",
+	Short: "Show the JUnit test report of a build",
+	Long: `Shows the totals of passed, failed and skipped tests of a build, the
+failing test cases with their error details and stack traces and the tests
+which regressed since the previous build. The build defaults to lastBuild.
+
+With --junit-out the report is also written as JUnit XML, e.g. to open it
+in an IDE.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		jobName := args[0]
+		ref := ""
+		if len(args) > 1 {
+			ref = args[1]
+		}
+		buildUrl, err := Jenkins.ResolveBuildUrl(jobName, ref)
+		if err != nil {
+			log.Fatal("Error: Could not find build ", Jenkins.BuildUrl(jobName, ref), ": ", err)
+		}
+		report, previous, err := loadTestReports(buildUrl)
+		if err != nil {
+			log.Fatal("Error: Could not get test report of build ", buildUrl, ": ", err)
+		}
+		fmt.Print(formatTestReport(report, previous))
+
+		if testsJUnitOut != "" {
+			f, err := os.Create(testsJUnitOut)
+			if err != nil {
+				log.Fatal("Error: ", err)
+			}
+			defer f.Close()
+			if err := report.WriteJUnit(f); err != nil {
+				log.Fatal("Error: Could not write JUnit report: ", err)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(testsCmd)
+
+	testsCmd.Flags().StringVar(&testsJUnitOut, "junit-out", "", "Write the report as JUnit XML to this file.")
+}
+
+// loadTestReports fetches the test report of the build and, if available,
+// the one of the previous build to find regressions
+func loadTestReports(buildUrl string) (*jenkins.TestReport, *jenkins.TestReport, error) {
+	report, err := Jenkins.GetTestReport(buildUrl)
+	if err != nil {
+		return nil, nil, err
+	}
+	build, err := Jenkins.GetBuild(buildUrl)
+	if err != nil || build.PreviousBuild == nil {
+		return report, nil, nil
+	}
+	previous, err := Jenkins.GetTestReport(build.PreviousBuild.Url)
+	if err != nil {
+		log.Println("Info: No test report for previous build", build.PreviousBuild.Url, err)
+		return report, nil, nil
+	}
+	return report, previous, nil
+}
+
+// formatTestReport renders the totals, failures and regressions of a report
+func formatTestReport(report, previous *jenkins.TestReport) string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "%s • %s • %s\n",
+		passStyle.Render(fmt.Sprintf("%d passed", report.PassCount)),
+		failStyle.Render(fmt.Sprintf("%d failed", report.FailCount)),
+		skipStyle.Render(fmt.Sprintf("%d skipped", report.SkipCount)))
+
+	if regressions := report.Regressions(previous); len(regressions) > 0 {
+		s.WriteString("\n" + keywordStyle.Render("Regressions since the previous build:") + "\n")
+		for _, c := range regressions {
+			s.WriteString("  " + failStyle.Render("✗") + " " + c.FullName() + "\n")
+		}
+	}
+
+	for _, c := range report.Failed() {
+		s.WriteString("\n" + failStyle.Render("✗ "+c.FullName()))
+		if c.Age > 0 {
+			s.WriteString(subtleStyle.Render(fmt.Sprintf(" (failing for %d builds)", c.Age)))
+		}
+		s.WriteString("\n")
+		if c.ErrorDetails != "" {
+			s.WriteString(indent(c.ErrorDetails, "    ") + "\n")
+		}
+		if c.ErrorStackTrace != "" {
+			s.WriteString(subtleStyle.Render(indent(c.ErrorStackTrace, "    ")) + "\n")
+		}
+	}
+	return s.String()
+}
+
+// indent prefixes every line of text
+func indent(text, prefix string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	return prefix + strings.Join(lines, "\n"+prefix)
+}
+
+type testReportMsg string
+
+// loadTestTab fetches the test report of the build shown in the BuildModel
+func (m *BuildModel) loadTestTab() tea.Cmd {
+	return func() tea.Msg {
+		if m.BuildUrl == "" {
+			return testReportMsg("No build yet.")
+		}
+		report, previous, err := loadTestReports(m.BuildUrl)
+		if errors.Is(err, jenkins.ErrNoTestReport) {
+			return testReportMsg("This build has no test report (yet).")
+		}
+		if err != nil {
+			log.Println("Error:", err)
+			return testReportMsg("Could not load test report: " + err.Error())
+		}
+		return testReportMsg(formatTestReport(report, previous))
+	}
+}
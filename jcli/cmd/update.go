@@ -5,15 +5,12 @@ package cmd
 
 import (
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
-	"time"
-
 	"strings"
+	"time"
 
 	util "jcli/jenkins"
 
@@ -29,162 +26,310 @@ var (
 	doneStyle           = lipgloss.NewStyle().Margin(1, 1, 0)
 	helpStyle           = lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Margin(0, 1)
 	checkMark           = lipgloss.NewStyle().Foreground(lipgloss.Color("42")).SetString("✓")
+	activeTabStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("211")).Bold(true).Padding(0, 1)
+	tabStyle            = lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Padding(0, 1)
 )
 
 type BuildModel struct {
-	BuildUrl      string
-	File          string
-	JobName       string
+	BuildUrl string
+	File     string
+	JobName  string
+	// templateJob is copied to create the job if it does not exist yet
+	templateJob   string
+	logStart      int64
+	logTail       int
+	useHtml       bool
 	width         int
 	height        int
 	done          bool
 	statusMessage string
 	userScrolled  bool
+	showTests     bool
 	spinner       spinner.Model
 	viewport      viewport.Model
 	statusport    viewport.Model
+	testsport     viewport.Model
+	rawLog        string
+	showRaw       bool
+	filter        *util.LogFilter
+	logContent    string
+	search        logSearch
+	errorRegexp   *regexp.Regexp
+	// watcher rebuilds the job when the pipeline file changes
+	watcher         *fileWatcher
+	extraWatchPaths []string
+	abortOnChange   bool
+	// libs are local shared library checkouts by name, which replace the
+	// library scripts of the build
+	libs map[string]string
+	// run counts the builds started by watching, so that results of the
+	// previous run are ignored
+	run int
+	// reconnecting is shown instead of the status while Jenkins cannot be
+	// reached
+	reconnecting string
+	// filterError is shown below the status if the log filter of the config
+	// is invalid and the log is shown unfiltered
+	filterError string
 }
 
 type consoleOutput string
 type consoleFinish string
 type emptyUrl string
 
+// buildError is shown in the status bar if the build could not be started
+type buildError string
+
+// logError is returned if the log could not be fetched, it is fetched again
+// with the next poll
+type logError struct{ err error }
+
+// queueStatus is the state of the queue item of the triggered build. The
+// item is nil right after queueing the build.
+type queueStatus struct {
+	url  string
+	item *util.QueueItem
+	err  error
+}
+
+// replayStarted is the build started by replaying the last one with the
+// local libraries, with the library scripts which could not be replaced
+type replayStarted struct {
+	url     string
+	skipped []string
+}
+
+// buildMsg is the result of a command of a BuildModel. Results are broadcast
+// to all screens, so every BuildModel only handles the results it owns.
+type buildMsg struct {
+	model *BuildModel
+	run   int
+	msg   tea.Msg
+}
+
+// owned tags the result of the command with the model and the current run
+func (m *BuildModel) owned(cmd tea.Cmd) tea.Cmd {
+	run := m.run
+	return func() tea.Msg {
+		msg := cmd()
+		if msg == nil {
+			return nil
+		}
+		return buildMsg{model: m, run: run, msg: msg}
+	}
+}
+
 // var File string
-var FullLog string
 
 // updateCmd represents the update command
 var updateCmd = &cobra.Command{
-	Use:   "update",
+	Use:   "update [file]",
 	Short: "Update a Jenkins job with a new pipeline script",
-	Long:  ``,
+	Long: `Update a Jenkins job with the pipeline script in file and build it.
+The file defaults to Jenkinsfile. The job is looked up in the job mappings of
+the project config file .jcli.yaml, unless it is given with --job. Without a
+mapping, you are asked for the job. The changes to the pipeline script are
+shown before updating the job, unless --yes is given. With --watch, the job
+is updated and built again whenever the file is saved. With --lib, the build
+replays the last one with the scripts of local shared library checkouts. With
+--sandbox, your own copy of the job for the current git branch is updated
+instead, see the sandbox command.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		main()
+		file := "Jenkinsfile"
+		if len(args) > 0 {
+			file = args[0]
+		}
+		main(file)
 	},
 }
 
+var (
+	updateJob        string
+	updateSandbox    bool
+	updateWatch      bool
+	updateAbort      bool
+	updateWatchPaths []string
+	updateLibs       map[string]string
+	updateYes        bool
+)
+
+// applyUpdateFlags sets up a build model with the flags of the update command
+func applyUpdateFlags(m *BuildModel) error {
+	m.abortOnChange = m.abortOnChange || updateAbort
+	m.extraWatchPaths = append([]string{}, updateWatchPaths...)
+	m.libs = updateLibs
+	for _, dir := range updateLibs {
+		m.extraWatchPaths = append(m.extraWatchPaths, dir)
+	}
+	if updateWatch {
+		return m.startWatching()
+	}
+	return nil
+}
+
 func (m *BuildModel) initBuild() tea.Cmd {
 	return func() tea.Msg {
 		// Check if the job exists, create it if it doesn't
-		if !Jenkins.CheckJobsExist(m.JobName) {
+		exists, err := Jenkins.JobExists(m.JobName)
+		if err != nil {
+			log.Println("Error:", err)
+			return buildError("⚠️ Could not reach Jenkins: " + err.Error())
+		}
+		if !exists {
 			log.Println("Job", m.JobName, "does not exist")
 
-			err := Jenkins.CreateEmptyJob(m.JobName)
+			if m.templateJob != "" {
+				err = createJobFrom(m.JobName, m.templateJob)
+			} else {
+				err = Jenkins.CreateEmptyJob(m.JobName)
+			}
 			if err != nil {
 				log.Println("Error:", err)
-				log.Fatal("Error: Could not create job", m.JobName)
+				return buildError("⚠️ Could not create job " + m.JobName + ": " + err.Error())
 			}
 		}
 
-		// Check if the file exists
-		if _, err := os.Stat(m.File); os.IsNotExist(err) {
-			log.Println("Error: File does not exist.")
-			log.Fatal("Error: Could not read pipeline script from file", m.File)
-		}
 		log.Println("Info: Reading pipeline script from file", m.File)
 		newPipeline, err := util.LoadPipelineScriptFromFile(filepath.Clean(m.File))
 		if err != nil {
 			log.Println("Error:", err)
-			log.Fatal("Error: Could not read pipeline script from file", m.File)
+			return buildError("⚠️ Could not read the pipeline script from " + m.File + ": " + err.Error())
+		}
+		config, err := Jenkins.GetJobConfig(m.JobName)
+		if err != nil {
+			log.Println("Error:", err)
+			return buildError("⚠️ Could not get the config of " + m.JobName + ": " + err.Error())
+		}
+		updatedScript, err := util.ReplacePipelineScript(config, newPipeline)
+		if err != nil {
+			log.Println("Error:", err)
+			return buildError("⚠️ Could not update the pipeline script: " + err.Error())
 		}
-		config, _ := Jenkins.GetJobConfig(m.JobName)
-		updatedScript, _ := util.ReplacePipelineScript(config, newPipeline)
 
-		Jenkins.UpdateJobConfig(m.JobName, updatedScript)
+		if err := Jenkins.UpdateJobConfig(m.JobName, updatedScript); err != nil {
+			log.Println("Error:", err)
+			return buildError("⚠️ Could not update job " + m.JobName + ": " + err.Error())
+		}
 		log.Println("Info: Updated pipeline script for job", m.JobName)
 		// Trigger a build
-		m.statusMessage = "💤 Waiting for job to start..."
-		buildUrl := Jenkins.TriggerBuild(m.JobName)
-		// log.Println("Info: Build URL:", buildUrl)
-		time.Sleep(1 * time.Second)
-		// Stream the output of the build console to the terminal
-		log.Println("Info: Build URL inside initBuild:", buildUrl)
-		m.BuildUrl = buildUrl
-		m.statusMessage = "👷 Executing build..."
+		if len(m.libs) > 0 {
+			buildUrl, skipped, err := m.replayWithLibraries(newPipeline)
+			if err != nil {
+				log.Println("Error:", err)
+				return buildError("⚠️ Could not trigger a build: " + err.Error())
+			}
+			return replayStarted{url: buildUrl, skipped: skipped}
+		}
+		queueUrl, err := Jenkins.QueueBuild(m.JobName)
+		if err != nil {
+			log.Println("Error:", err)
+			return buildError("⚠️ Could not trigger a build: " + err.Error())
+		}
+		return queueStatus{url: queueUrl}
+	}
+}
 
-		return consoleOutput("No console output yet...")
+// pollQueue fetches the state of the queue item of the triggered build
+func (m *BuildModel) pollQueue(queueUrl string) tea.Cmd {
+	return func() tea.Msg {
+		time.Sleep(time.Second)
+		item, err := Jenkins.GetQueueItem(queueUrl)
+		return queueStatus{url: queueUrl, item: item, err: err}
 	}
 }
 
-func (m *BuildModel) GetBuildOutput(filterOutput bool) tea.Cmd {
+func (m *BuildModel) GetBuildOutput() tea.Cmd {
 	return func() tea.Msg {
 		time.Sleep(3 * time.Second)
 		if m.BuildUrl == "" {
 			return emptyUrl("No build URL found. Need to trigger build first.")
 		}
-		// Setup console output request
-		req, err := http.NewRequest("GET", m.BuildUrl+"/logText/progressiveText", nil)
-		if err != nil {
-			log.Println("Error:", err)
-		}
-		req.SetBasicAuth(Jenkins.User, Jenkins.APIKey)
-		client := &http.Client{}
-
-		// Send the request
-		resp, err := client.Do(req)
+		// Fetch the raw console output. The filters are applied when showing
+		// it, so that the raw log can be shown without fetching it again.
+		rawLog, moreData, err := m.fetchLog()
 		if err != nil {
 			log.Println("Error:", err)
 			log.Println("Error: Could not connect to Jenkins server. Please check the address and try again.")
-		}
-		defer resp.Body.Close()
-		// Read the response
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Println("Error:", err)
-			m.done = true
-		}
-		// Get the new log as raw
-		rawLog := string(body)
-		var cleanedLog string
-		// Remove the [Pipeline] part from the console output
-		if filterOutput {
-			cleanedLog = removePipelinePart(rawLog)
-		} else {
-			cleanedLog = rawLog
+			// Keep the current log and try again with the next poll
+			return logError{err}
 		}
 
 		// Check if the build is still running
-		xMoreData := resp.Header.Get("X-More-Data")
-		if xMoreData != "true" {
-			// m.done = true
-			return consoleFinish(cleanedLog)
-		}
-
-		// Check if the full log is empty
-		var newLog string
-		if FullLog == "" {
-			log.Println("Setting full log to new log")
-			FullLog = strings.Clone(cleanedLog)
-			newLog = FullLog
-		} else {
-			// If the full log is not empty, get the new logText
-			newLog = strings.ReplaceAll(cleanedLog, FullLog, "")
-			FullLog = strings.Clone(cleanedLog)
-		}
-		_ = newLog
-		return consoleOutput(FullLog)
+		if !moreData {
+			return consoleFinish(rawLog)
+		}
+		return consoleOutput(rawLog)
+	}
+}
+
+// fetchLog fetches the console log of the build without the encoded
+// console notes
+func (m *BuildModel) fetchLog() (string, bool, error) {
+	if m.useHtml {
+		html, _, moreData, err := Jenkins.GetProgressiveHtml(m.BuildUrl, m.logStart)
+		return util.HTMLToANSI(html, m.BuildUrl, false), moreData, err
+	}
+	text, _, moreData, err := Jenkins.GetProgressiveText(m.BuildUrl, m.logStart)
+	return util.StripConsoleNotes(text), moreData, err
+}
 
+// showLog filters the raw log unless the raw view is active and writes it
+// into the viewport
+func (m *BuildModel) showLog() {
+	content := m.rawLog
+	if !m.showRaw {
+		content = m.filter.Apply(content)
 	}
+	content = tailLines(content, m.logTail)
+	m.setLogContent(util.SanitizeANSI(content))
 }
 
-// removePipelinePart removes the [Pipeline] part from the console output
-func removePipelinePart(consoleOutput string) string {
-	regexp := regexp.MustCompile(`(?m)\[Pipeline\].*\n`)
-	res := regexp.ReplaceAllString(consoleOutput, "")
-	return res
+// newLogFilter creates the log filter set up in the config file
+func newLogFilter() (*util.LogFilter, error) {
+	var rules []util.FilterRule
+	for _, r := range Config.LogFilter.Rules {
+		rule := util.FilterRule{Action: util.FilterExclude}
+		pattern := r.Exclude
+		switch {
+		case r.Include != "":
+			rule.Action, pattern = util.FilterInclude, r.Include
+		case r.Rewrite != "":
+			rule.Action, pattern, rule.Replacement = util.FilterRewrite, r.Rewrite, r.Replace
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log filter pattern %q: %w", pattern, err)
+		}
+		rule.Pattern = re
+		rules = append(rules, rule)
+	}
+	return util.NewLogFilter(Config.LogFilter.Presets, rules)
 }
 
 func init() {
 	rootCmd.AddCommand(updateCmd)
+	updateCmd.Flags().StringVarP(&updateJob, "job", "j", "", "Job to update instead of the one the file is mapped to.")
+	updateCmd.Flags().BoolVarP(&updateSandbox, "sandbox", "s", false, "Update your sandbox job of the current git branch, creating it on first use.")
+	updateCmd.MarkFlagsMutuallyExclusive("job", "sandbox")
+	updateCmd.Flags().BoolVarP(&updateWatch, "watch", "w", false, "Update the job and build again whenever the file changes.")
+	updateCmd.Flags().BoolVar(&updateAbort, "abort", false, "Abort the running build when the file changes.")
+	updateCmd.Flags().StringToStringVar(&updateLibs, "lib", nil, "Local checkout of a shared library as name=path, which replaces the library scripts by replaying the last build. Can be repeated.")
+	updateCmd.Flags().BoolVarP(&updateYes, "yes", "y", false, "Update the job without showing the changes first.")
+	updateCmd.Flags().StringSliceVar(&updateWatchPaths, "watch-path", nil, "Additional files or directories to watch, like shared library sources.")
 }
 
-func NewBuildModel(filename string, width int, height int) *BuildModel {
+func NewBuildModel(filename string, jobName string, width int, height int) *BuildModel {
 	// Setup viewport initial dimensions. Will be set to full screen size in the first update.
-	vp := viewport.New(width-3, height-7)
+	vp := viewport.New(width-3, height-8)
 	vp.Style = lipgloss.NewStyle().
 		BorderStyle(lipgloss.DoubleBorder()).
 		Margin(1, 1, 0).
 		BorderForeground(lipgloss.Color("241"))
 	vp.SetContent("No console output yet...")
+	// Setup the tests tab with the same look as the log
+	tp := viewport.New(width-3, height-8)
+	tp.Style = vp.Style
 	// Setup statusport
 	stp := viewport.New(width-3, 2)
 	stp.Style = lipgloss.NewStyle().
@@ -198,39 +343,117 @@ func NewBuildModel(filename string, width int, height int) *BuildModel {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("63"))
-	// Get the job name from the filename
-	jobName := filepath.Base(filename)
-	jobName = strings.TrimSuffix(jobName, filepath.Ext(jobName))
+	filter, err := newLogFilter()
+	filterError := ""
+	if err != nil {
+		log.Println("Error:", err)
+		filterError = "⚠️ Showing the unfiltered log: " + err.Error()
+	}
 	return &BuildModel{
 		File:          filename,
+		filter:        filter,
+		filterError:   filterError,
 		JobName:       jobName,
 		spinner:       s,
 		viewport:      vp,
 		statusport:    stp,
+		testsport:     tp,
+		search:        newLogSearch(),
+		errorRegexp:   compileErrorPatterns(Config.ErrorPatterns),
+		abortOnChange: Config.Watch.Abort,
 		userScrolled:  false,
 		statusMessage: "⌚ Triggering job ...",
 	}
 }
 
+// NewBuildModelFromUrl creates a BuildModel which shows the log of an
+// existing build instead of triggering a new one
+func NewBuildModelFromUrl(jobName, buildUrl string, width int, height int) *BuildModel {
+	m := NewBuildModel("", jobName, width, height)
+	m.BuildUrl = buildUrl
+	m.statusMessage = "👷 Executing build..."
+	return m
+}
+
 func (m *BuildModel) Init() tea.Cmd {
-	return tea.Batch(m.GetBuildOutput(true), m.spinner.Tick)
+	cmds := []tea.Cmd{m.owned(m.GetBuildOutput()), m.spinner.Tick}
+	if m.watcher != nil {
+		cmds = append(cmds, m.watcher.wait())
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m *BuildModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	var statuscmd tea.Cmd
 	var cmds []tea.Cmd
+	if owned, ok := msg.(buildMsg); ok {
+		if owned.model != m || owned.run != m.run {
+			return m, nil
+		}
+		msg = owned.msg
+	}
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width, m.height = msg.Width, msg.Height
-		m.viewport.Height = m.height - 7
+		m.viewport.Height = m.height - 8
 		m.viewport.Width = m.width - 3
+		m.testsport.Height = m.height - 8
+		m.testsport.Width = m.width - 3
 		m.statusport.Height = m.height - 7
 		m.statusport.Width = m.width - 3
 	case tea.KeyMsg:
+		if m.search.editing {
+			return m, m.updateSearch(msg)
+		}
+		if m.showTests {
+			switch msg.String() {
+			case "esc", "q":
+				return m, m.close()
+			case "t":
+				m.showTests = false
+			default:
+				m.testsport, cmd = m.testsport.Update(msg)
+			}
+			return m, cmd
+		}
 		switch msg.String() {
-		case "ctrl+c", "esc", "q":
-			return m, tea.Quit
+		case "q":
+			return m, m.close()
+		case "esc":
+			if m.search.active() {
+				m.clearSearch()
+				return m, nil
+			}
+			return m, m.close()
+		case "/": // Search the log
+			return m, m.startSearch()
+		case "n":
+			m.jumpToMatch(1)
+			return m, nil
+		case "N":
+			m.jumpToMatch(-1)
+			return m, nil
+		case "e": // Jump to the next or previous error
+			m.jumpToError(false)
+			return m, nil
+		case "E":
+			m.jumpToError(true)
+			return m, nil
+		case "s", "S": // Save the log as text or as html
+			return m, m.owned(m.saveLog(msg.String() == "S"))
+		case "f": // Toggle between the filtered and the raw log
+			m.showRaw = !m.showRaw
+			// The lines of the errors differ between the views
+			m.search.lastError = -1
+			m.showLog()
+			return m, nil
+		case "w": // Rebuild when the pipeline file changes
+			return m, m.toggleWatch()
+		case "t": // Show the test report of the build
+			m.showTests = true
+			m.testsport.SetContent("Loading test report...")
+			cmds = append(cmds, m.owned(m.loadTestTab()))
 		case "k", "up", "j", "down", "home", "end":
 			m.userScrolled = true
 		case "ctrl+u", "pageup":
@@ -246,34 +469,124 @@ func (m *BuildModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "o": // Open the build in the browser
 			util.Openbrowser(m.BuildUrl)
 		}
+	case filesChanged:
+		if msg.watcher != m.watcher {
+			return m, nil
+		}
+		log.Println("Info: Changed", strings.Join(msg.paths, ", "))
+		m.statusMessage = "🔍 Linting " + m.File + "..."
+		return m, tea.Batch(m.watcher.wait(), m.owned(m.lintPipeline()))
+	case lintResult:
+		if msg.err != nil {
+			log.Println("Error:", msg.err)
+			m.statusMessage = "⚠️ Could not lint the pipeline: " + msg.err.Error()
+			return m, nil
+		}
+		if len(msg.errors) > 0 {
+			// Stop following the previous build and show the errors instead
+			m.run++
+			m.statusMessage = "❌ The pipeline is invalid, fix it and save again"
+			m.rawLog = ""
+			m.setLogContent("Errors found in " + m.File + ":\n\n" + strings.Join(msg.errors, "\n"))
+			m.viewport.GotoTop()
+			return m, nil
+		}
+		return m, m.restartBuild()
+	case reconnecting:
+		if !m.done {
+			m.reconnecting = msg.String()
+		}
+		return m, nil
+	case logError:
+		m.reconnecting = "🔌 Reconnecting to Jenkins… " + msg.err.Error()
+		return m, m.owned(m.GetBuildOutput())
+	case queueStatus:
+		m.reconnecting = ""
+		switch {
+		case msg.err != nil:
+			log.Println("Error:", msg.err)
+			m.reconnecting = "🔌 Reconnecting to Jenkins… " + msg.err.Error()
+		case msg.item == nil:
+			// Just queued, poll the item from now on
+			m.statusMessage = "💤 Waiting for job to start..."
+		case msg.item.Cancelled:
+			m.statusMessage = "🚫 The build was cancelled while waiting in the queue"
+			m.done = true
+			return m, nil
+		case msg.item.Executable != nil:
+			log.Println("Info: Build URL:", msg.item.Executable.Url)
+			m.BuildUrl = msg.item.Executable.Url
+			m.statusMessage = "👷 Executing build..."
+			return m, m.owned(m.GetBuildOutput())
+		case msg.item.Why != "":
+			// Show why the build waits, like the label without free executor
+			m.statusMessage = "💤 " + strings.Join(strings.Fields(msg.item.Why), " ")
+		}
+		return m, m.owned(m.pollQueue(msg.url))
+	case replayStarted:
+		log.Println("Info: Build URL:", msg.url)
+		m.BuildUrl = msg.url
+		m.statusMessage = "👷 Executing build..."
+		if len(msg.skipped) > 0 {
+			m.statusMessage += " ⚠️ Not loaded by the last build and not replaced: " + strings.Join(msg.skipped, ", ")
+		}
+		return m, m.owned(m.GetBuildOutput())
+	case buildError:
+		m.statusMessage = string(msg)
+		m.done = true
+		return m, nil
 	case emptyUrl:
 		log.Println("Empty URL")
-		cmds = append(cmds, m.initBuild())
+		cmds = append(cmds, m.owned(m.initBuild()))
 	case consoleFinish:
 		// Build finished
+		m.reconnecting = ""
 		m.statusMessage = checkMark.Render() + " Build finished!"
-		m.viewport.SetContent(string(msg))
+		m.rawLog = string(msg)
+		m.showLog()
 		if !m.userScrolled {
 			m.viewport.GotoBottom()
 		}
 		m.viewport, cmd = m.viewport.Update(msg)
 		m.done = true
+		if m.showTests {
+			return m, tea.Batch(cmd, m.owned(m.loadTestTab()))
+		}
 		return m, cmd
+	case statusFlash:
+		// Show the message for a few seconds, then restore the build status
+		previous := m.statusMessage
+		m.statusMessage = string(msg)
+		m.statusport.SetContent(m.statusMessage)
+		return m, m.owned(tea.Tick(3*time.Second, func(time.Time) tea.Msg {
+			return restoreStatus{message: string(msg), previous: previous}
+		}))
+	case restoreStatus:
+		if m.statusMessage == msg.message {
+			m.statusMessage = msg.previous
+			m.statusport.SetContent(m.status())
+		}
+		return m, nil
+	case testReportMsg:
+		m.testsport.SetContent(string(msg))
+		return m, nil
 	case consoleOutput:
-		m.viewport.SetContent(string(msg))
+		m.reconnecting = ""
+		m.rawLog = string(msg)
+		m.showLog()
 		// If user scrolled manually, don't auto-scroll
 		if !m.userScrolled {
 			m.viewport.GotoBottom()
 		}
 
-		cmds = append(cmds, m.GetBuildOutput(true))
+		cmds = append(cmds, m.owned(m.GetBuildOutput()))
 	case spinner.TickMsg:
 		m.spinner, cmd = m.spinner.Update(msg)
-		m.statusport.SetContent(m.spinner.View() + m.statusMessage)
+		m.statusport.SetContent(m.spinner.View() + m.status())
 		m.statusport, statuscmd = m.statusport.Update(msg)
 		cmds = append(cmds, cmd, statuscmd)
 		if m.done {
-			m.statusport.SetContent(m.statusMessage)
+			m.statusport.SetContent(m.status())
 			log.Println("Build done")
 			return m, nil
 		}
@@ -284,24 +597,120 @@ func (m *BuildModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// status returns the status of the build, or that Jenkins cannot be reached
+func (m *BuildModel) status() string {
+	status := m.statusMessage
+	if m.reconnecting != "" && !m.done {
+		status = m.reconnecting
+	}
+	if m.filterError != "" {
+		status += "\n" + m.filterError
+	}
+	return status
+}
+
+func (m *BuildModel) Title() string {
+	return "Build " + m.JobName
+}
+
+func (m *BuildModel) Help() []keyHelp {
+	return []keyHelp{
+		{"a/G", "auto-scroll"},
+		{"j/k", "scroll down/up"},
+		{"ctrl+d/u", "page down/up"},
+		{"/", "search the log"},
+		{"n/N", "next/previous match"},
+		{"e/E", "next/previous error"},
+		{"f", "toggle raw/filtered log"},
+		{"s/S", "save the log as text/html"},
+		{"t", "toggle the test report"},
+		{"w", "watch the file and rebuild on changes"},
+		{"o", "open the build in the browser"},
+	}
+}
+
+// CapturesInput reports whether the search prompt is open
+func (m *BuildModel) CapturesInput() bool {
+	return m.search.editing
+}
+
 func (m BuildModel) View() string {
-	help := helpStyle.Render(fmt.Sprintf("\n\n a/G: auto-scroll • j/↓: down • k/↑: up c+u/p-up: page up • c+d/p-down: page down •q: exit\n"))
-	if m.done {
-		return m.viewport.View() + m.statusport.View() + help
+	help := helpStyle.Render(fmt.Sprintf("\n\n a/G: auto-scroll • j/↓: down • k/↑: up c+u/p-up: page up • c+d/p-down: page down • /: search • e/E: next/prev error • f: raw/filtered • s/S: save log/html • t: tests • w: watch • ?: help • q: exit\n"))
+	if m.showTests {
+		tabs := tabStyle.Render("Log") + activeTabStyle.Render("Tests")
+		return mainStyle.Render(tabs) + m.testsport.View() + m.statusport.View() + help
+	}
+	tabs := activeTabStyle.Render("Log") + tabStyle.Render("Tests")
+	if m.showRaw {
+		tabs += subtleStyle.Render(" (raw)")
+	}
+	if m.watcher != nil {
+		tabs += subtleStyle.Render(" 👀 watching")
 	}
-	return m.viewport.View() + m.statusport.View() + help
+	if search := m.searchView(); search != "" {
+		tabs += "  " + search
+	}
+	return mainStyle.Render(tabs) + m.viewport.View() + m.statusport.View() + help
+}
+
+// statusFlash is shown in the status bar for a few seconds
+type statusFlash string
+
+// restoreStatus restores the previous status unless it changed meanwhile
+type restoreStatus struct {
+	message, previous string
 }
 
-func main() {
+// saveLog saves the full, unfiltered log of the build in the current directory
+func (m *BuildModel) saveLog(asHtml bool) tea.Cmd {
+	return func() tea.Msg {
+		if m.BuildUrl == "" {
+			return statusFlash("⚠️ No build to save yet")
+		}
+		format := "text"
+		if asHtml {
+			format = "html"
+		}
+		path, err := saveBuildLog(m.JobName, m.BuildUrl, "", format, false)
+		if err != nil {
+			log.Println("Error:", err)
+			return statusFlash("⚠️ Could not save log: " + err.Error())
+		}
+		return statusFlash("💾 Saved log to " + path)
+	}
+}
+
+func main(file string) {
 	f, err := tea.LogToFile("lazyjenkins.log", "console")
 	if err != nil {
 		fmt.Println("fatal:", err)
 		os.Exit(1)
 	}
 	defer f.Close()
-	testFile := "Jenkinsfile"
 	width, height := 80, 24
-	if _, err := tea.NewProgram(NewBuildModel(testFile, width, height), tea.WithMouseCellMotion(), tea.WithAltScreen()).Run(); err != nil {
+	jobName, templateJob := updateJob, ""
+	if updateSandbox {
+		jobName, templateJob, err = sandboxJob(file)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
+	if jobName == "" {
+		jobName, _ = mappedJob(file)
+	}
+	// Ask for the job if the file is not mapped
+	var root tea.Model = NewJobPromptModel(file, width, height)
+	if jobName != "" {
+		m := NewBuildModel(file, jobName, width, height)
+		m.templateJob = templateJob
+		if err := applyUpdateFlags(m); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		root = confirmUpdate(m)
+	}
+	if _, err := tea.NewProgram(NewMainModel(root), tea.WithMouseCellMotion(), tea.WithAltScreen()).Run(); err != nil {
 		fmt.Println("Error running program:", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	util "jcli/jenkins"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileWatcher reports changes of files and of the files in directories
+type fileWatcher struct {
+	watcher  *fsnotify.Watcher
+	files    map[string]bool
+	dirs     []string
+	debounce time.Duration
+}
+
+// filesChanged is sent after the watched files changed and no further
+// change happened for the debounce duration
+type filesChanged struct {
+	watcher *fileWatcher
+	paths   []string
+}
+
+// lintResult holds the errors found in the pipeline before a rebuild
+type lintResult struct {
+	errors []string
+	err    error
+}
+
+// newFileWatcher watches the files and directories. Directories are watched
+// with all their subdirectories.
+func newFileWatcher(paths []string, debounce time.Duration) (*fileWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &fileWatcher{watcher: watcher, files: map[string]bool{}, debounce: debounce}
+	watched := map[string]bool{}
+	add := func(dir string) error {
+		if watched[dir] {
+			return nil
+		}
+		watched[dir] = true
+		return watcher.Add(dir)
+	}
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		if !info.IsDir() {
+			// Editors often replace the file on save, so watch its directory
+			w.files[abs] = true
+			err = add(filepath.Dir(abs))
+		} else {
+			w.dirs = append(w.dirs, abs)
+			err = filepath.WalkDir(abs, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || !d.IsDir() {
+					return err
+				}
+				if path != abs && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return add(path)
+			})
+		}
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// relevant reports whether the event changes one of the watched files
+func (w *fileWatcher) relevant(event fsnotify.Event) bool {
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+		return false
+	}
+	if w.files[event.Name] {
+		return true
+	}
+	// Skip hidden files and the backup and swap files of editors
+	name := filepath.Base(event.Name)
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") || strings.HasSuffix(name, ".swp") {
+		return false
+	}
+	for _, dir := range w.dirs {
+		if strings.HasPrefix(event.Name, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// wait blocks until the watched files changed. It returns nil once the
+// watcher is closed.
+func (w *fileWatcher) wait() tea.Cmd {
+	return func() tea.Msg {
+		changed := map[string]bool{}
+		var quiet <-chan time.Time
+		for {
+			select {
+			case event, ok := <-w.watcher.Events:
+				if !ok {
+					return nil
+				}
+				if !w.relevant(event) {
+					continue
+				}
+				changed[event.Name] = true
+				quiet = time.After(w.debounce)
+			case err, ok := <-w.watcher.Errors:
+				if !ok {
+					return nil
+				}
+				log.Println("Error:", err)
+			case <-quiet:
+				paths := make([]string, 0, len(changed))
+				for path := range changed {
+					paths = append(paths, path)
+				}
+				sort.Strings(paths)
+				return filesChanged{watcher: w, paths: paths}
+			}
+		}
+	}
+}
+
+func (w *fileWatcher) Close() error {
+	return w.watcher.Close()
+}
+
+// watchPaths returns the pipeline file, the paths of the config relative
+// to the project and the extra paths of the model
+func (m *BuildModel) watchPaths() []string {
+	paths := []string{m.File}
+	for _, p := range Config.Watch.Paths {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(Config.ProjectDir(), p)
+		}
+		paths = append(paths, p)
+	}
+	return append(paths, m.extraWatchPaths...)
+}
+
+// startWatching rebuilds the job whenever the pipeline file changes
+func (m *BuildModel) startWatching() error {
+	if m.File == "" {
+		return errors.New("there is no pipeline file to watch")
+	}
+	w, err := newFileWatcher(m.watchPaths(), Config.Watch.Debounce)
+	if err != nil {
+		return err
+	}
+	m.watcher = w
+	return nil
+}
+
+// toggleWatch starts or stops watching the pipeline file
+func (m *BuildModel) toggleWatch() tea.Cmd {
+	if m.watcher != nil {
+		m.watcher.Close()
+		m.watcher = nil
+		return nil
+	}
+	if err := m.startWatching(); err != nil {
+		log.Println("Error:", err)
+		return m.owned(func() tea.Msg { return statusFlash("⚠️ Cannot watch: " + err.Error()) })
+	}
+	return m.watcher.wait()
+}
+
+// close stops watching and returns to the previous screen
+func (m *BuildModel) close() tea.Cmd {
+	if m.watcher != nil {
+		m.watcher.Close()
+		m.watcher = nil
+	}
+	return popScreen
+}
+
+// lintPipeline validates the pipeline file on the server. Scripted
+// pipelines and servers without linter are not checked.
+func (m *BuildModel) lintPipeline() tea.Cmd {
+	return func() tea.Msg {
+		script, err := util.LoadPipelineScriptFromFile(filepath.Clean(m.File))
+		if err != nil {
+			return lintResult{err: err}
+		}
+		if !util.IsDeclarative(script) {
+			return lintResult{}
+		}
+		messages, err := Jenkins.ValidatePipeline(script)
+		if errors.Is(err, util.ErrLinterUnavailable) {
+			log.Println("Info:", err)
+			return lintResult{}
+		}
+		return lintResult{errors: messages, err: err}
+	}
+}
+
+// restartBuild resets the log view, then updates the job and builds it
+// again. The running build is aborted first if configured.
+func (m *BuildModel) restartBuild() tea.Cmd {
+	previous, abort := m.BuildUrl, m.abortOnChange && !m.done && m.BuildUrl != ""
+	// Results of the commands of the previous run are dropped from now on
+	m.run++
+	m.BuildUrl = ""
+	m.rawLog = ""
+	m.logStart = 0
+	m.done = false
+	m.userScrolled = false
+	m.clearSearch()
+	m.search.lastError = -1
+	m.setLogContent("No console output yet...")
+	m.viewport.GotoTop()
+	m.statusMessage = "🔁 Updating job..."
+	return tea.Batch(m.spinner.Tick, m.owned(func() tea.Msg {
+		if abort {
+			if err := Jenkins.AbortBuild(previous); err != nil {
+				log.Println("Error: Could not abort", previous, err)
+			}
+		}
+		return m.initBuild()()
+	}))
+}
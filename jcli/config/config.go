@@ -0,0 +1,156 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultErrorPatterns are used if the config file does not set any
+var DefaultErrorPatterns = []string{"ERROR", "FAILED", "Exception"}
+
+// DefaultFilterPresets are used if the config file does not set any
+var DefaultFilterPresets = []string{"pipeline"}
+
+// Config holds the settings read from the jcli config file
+type Config struct {
+	// ErrorPatterns are the regular expressions of log lines the build
+	// log viewer jumps to when looking for the next error
+	ErrorPatterns []string `yaml:"error_patterns"`
+	// LogFilter configures which lines of the build log are shown
+	LogFilter LogFilter `yaml:"log_filter"`
+	// Dashboard lists the jobs shown on the dashboard
+	Dashboard Dashboard `yaml:"dashboard"`
+	// Sandbox configures the per-developer jobs of update --sandbox
+	Sandbox Sandbox `yaml:"sandbox"`
+	// Watch configures update --watch
+	Watch Watch `yaml:"watch"`
+	// Jobs maps the pipeline files of the project to Jenkins jobs
+	Jobs []JobMapping `yaml:"jobs"`
+
+	// ProjectFile is the path of the project config file read over the
+	// config file, if any
+	ProjectFile string `yaml:"-"`
+}
+
+// Dashboard lists jobs and folders whose jobs are watched on the dashboard
+//
+//	dashboard:
+//	  jobs: [deploy, team/app/main]
+//	  folders: [team/libs]
+//	  interval: 10s
+type Dashboard struct {
+	Jobs     []string      `yaml:"jobs"`
+	Folders  []string      `yaml:"folders"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// DefaultSandboxJob is the job template of sandboxes if the config file does
+// not set one
+const DefaultSandboxJob = `sandbox/{{ .User }}/{{ .Project }}/{{ .Branch | replace "/" "-" }}`
+
+// Sandbox configures the jobs created for every developer and branch.
+// Job is a job template like the ones of the job mappings, which has to use
+// the branch in the job name and not in a folder. New sandboxes copy the
+// configuration of the Template job, or of the job the file is mapped to.
+// Sandboxes of branches merged into Base are pruned.
+//
+//	sandbox:
+//	  job: 'sandbox/{{ .User }}/{{ .Project }}/{{ .Branch | replace "/" "-" }}'
+//	  template: templates/pipeline
+//	  base: main
+type Sandbox struct {
+	Job      string `yaml:"job"`
+	Template string `yaml:"template"`
+	Base     string `yaml:"base"`
+}
+
+// Watch configures how update --watch rebuilds on changes. Paths are extra
+// files and directories, like shared library sources, relative to the
+// project directory. With Abort set, the running build is aborted before
+// the new one starts.
+//
+// Timeout is how long to wait for a replayed build to leave the queue.
+//
+//	watch:
+//	  debounce: 500ms
+//	  abort: true
+//	  paths: [vars, src]
+//	  timeout: 10m
+type Watch struct {
+	Debounce time.Duration `yaml:"debounce"`
+	Abort    bool          `yaml:"abort"`
+	Paths    []string      `yaml:"paths"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// LogFilter lists the enabled built-in presets and the user-defined rules
+//
+//	log_filter:
+//	  presets: [pipeline, timestamps, shell-echo]
+//	  rules:
+//	    - exclude: '^Downloading '
+//	    - rewrite: 'password=\S+'
+//	      replace: 'password=***'
+type LogFilter struct {
+	Presets []string     `yaml:"presets"`
+	Rules   []FilterRule `yaml:"rules"`
+}
+
+// FilterRule sets exactly one of Include, Exclude or Rewrite to a regular
+// expression. Replace is the replacement of a rewrite rule and may refer to
+// capture groups like $1.
+type FilterRule struct {
+	Include string `yaml:"include"`
+	Exclude string `yaml:"exclude"`
+	Rewrite string `yaml:"rewrite"`
+	Replace string `yaml:"replace"`
+}
+
+// DefaultPath returns the path of the config file in the home directory
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".jcli.yaml"
+	}
+	return filepath.Join(home, ".jcli.yaml")
+}
+
+// Load reads the config file at path. A missing file is not an error,
+// the defaults are used instead.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.ErrorPatterns) == 0 {
+		cfg.ErrorPatterns = DefaultErrorPatterns
+	}
+	if cfg.Dashboard.Interval <= 0 {
+		cfg.Dashboard.Interval = 10 * time.Second
+	}
+	if cfg.Watch.Debounce <= 0 {
+		cfg.Watch.Debounce = 500 * time.Millisecond
+	}
+	if cfg.Watch.Timeout <= 0 {
+		cfg.Watch.Timeout = 10 * time.Minute
+	}
+	if cfg.Sandbox.Job == "" {
+		cfg.Sandbox.Job = DefaultSandboxJob
+	}
+	if cfg.Sandbox.Base == "" {
+		cfg.Sandbox.Base = "main"
+	}
+	if cfg.LogFilter.Presets == nil {
+		cfg.LogFilter.Presets = DefaultFilterPresets
+	}
+	return cfg, nil
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFileName is the name of the project config file, which is searched
+// in the working directory and its parents
+const ProjectFileName = ".jcli.yaml"
+
+// JobMapping maps local pipeline files to a Jenkins job. File is a path
+// relative to the project directory and may contain the wildcards of
+// path.Match. Job is a text/template of the job path, see JobVars.
+//
+//	jobs:
+//	  - file: Jenkinsfile
+//	    job: 'team/app/{{ .Branch | replace "/" "-" }}'
+//	  - file: ci/*.groovy
+//	    job: team/app-{{ .Base }}
+type JobMapping struct {
+	File string `yaml:"file"`
+	Job  string `yaml:"job"`
+}
+
+// JobVars are the values available in the job templates
+type JobVars struct {
+	// Branch is the current git branch of the file
+	Branch string
+	// Dir is the directory of the file relative to the project directory
+	Dir string
+	// Base is the file name without extension
+	Base string
+	// User is the Jenkins user
+	User string
+	// Project is the name of the project directory
+	Project string
+}
+
+var templateFuncs = template.FuncMap{
+	"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"lower":   strings.ToLower,
+}
+
+// FindProjectFile returns the path of the project config file in dir or the
+// closest parent directory, or an empty string if there is none
+func FindProjectFile(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		file := filepath.Join(dir, ProjectFileName)
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadProject reads the project config file over the settings already
+// loaded, so the project overrides the settings it sets
+func (c *Config) LoadProject(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return err
+	}
+	c.ProjectFile, err = filepath.Abs(file)
+	return err
+}
+
+// ProjectDir returns the directory the job mappings are relative to
+func (c *Config) ProjectDir() string {
+	if c.ProjectFile != "" {
+		return filepath.Dir(c.ProjectFile)
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// ProjectPath returns the path of the project config file. Without one, it
+// is the path a new file is created at.
+func (c *Config) ProjectPath() string {
+	if c.ProjectFile != "" {
+		return c.ProjectFile
+	}
+	return filepath.Join(c.ProjectDir(), ProjectFileName)
+}
+
+// RelativePath returns the path of the file relative to the project
+// directory with forward slashes
+func (c *Config) RelativePath(file string) (string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(c.ProjectDir(), abs)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%s is outside of the project directory %s", file, c.ProjectDir())
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// FileVars returns the variables derived from the path of the file. The
+// branch and user have to be set by the caller.
+func (c *Config) FileVars(file string) (JobVars, error) {
+	rel, err := c.RelativePath(file)
+	if err != nil {
+		return JobVars{}, err
+	}
+	base := path.Base(rel)
+	return JobVars{
+		Dir:     path.Dir(rel),
+		Base:    strings.TrimSuffix(base, path.Ext(base)),
+		Project: filepath.Base(c.ProjectDir()),
+	}, nil
+}
+
+// MapJob returns the job the file is mapped to by the first matching
+// mapping. It reports false if no mapping matches.
+func (c *Config) MapJob(file string, vars JobVars) (string, bool, error) {
+	rel, err := c.RelativePath(file)
+	if err != nil {
+		// Files outside of the project are never mapped
+		return "", false, nil
+	}
+	for _, mapping := range c.Jobs {
+		if ok, _ := path.Match(path.Clean(mapping.File), rel); !ok {
+			continue
+		}
+		job, err := ExpandJob(mapping.Job, vars)
+		if err != nil {
+			return "", false, err
+		}
+		return job, true, nil
+	}
+	return "", false, nil
+}
+
+// ExpandJob executes the job template with the variables
+func ExpandJob(job string, vars JobVars) (string, error) {
+	tmpl, err := template.New("job").Funcs(templateFuncs).Option("missingkey=error").Parse(job)
+	if err != nil {
+		return "", fmt.Errorf("invalid job template %q: %w", job, err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return "", fmt.Errorf("invalid job template %q: %w", job, err)
+	}
+	return strings.Trim(out.String(), "/"), nil
+}
+
+// SaveJobMapping appends the mapping to the project config file, creating
+// the file in the project directory if there is none yet. Comments and the
+// other settings of the file are kept.
+func (c *Config) SaveJobMapping(mapping JobMapping) error {
+	file := c.ProjectPath()
+	var doc yaml.Node
+	data, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not a yaml mapping", file)
+	}
+
+	var jobs *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "jobs" {
+			jobs = root.Content[i+1]
+		}
+	}
+	if jobs == nil {
+		jobs = &yaml.Node{Kind: yaml.SequenceNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "jobs"}, jobs)
+	}
+	if jobs.Kind != yaml.SequenceNode {
+		// An empty jobs key is a null scalar
+		*jobs = yaml.Node{Kind: yaml.SequenceNode}
+	}
+	var entry yaml.Node
+	if err := entry.Encode(mapping); err != nil {
+		return err
+	}
+	jobs.Content = append(jobs.Content, &entry)
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, out.Bytes(), 0o644); err != nil {
+		return err
+	}
+	c.ProjectFile = file
+	c.Jobs = append(c.Jobs, mapping)
+	return nil
+}
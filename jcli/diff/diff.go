@@ -0,0 +1,216 @@
+// Package diff compares texts line by line and formats the differences as
+// unified diffs
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is the kind of an edit
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// Edit is a line which both texts share, or which was inserted or deleted
+type Edit struct {
+	Op   Op
+	Line string
+}
+
+// splitLines splits a text into lines without their line breaks
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Lines returns the shortest edit script turning a into b, computed with
+// the linear space variant of Myers' algorithm
+func Lines(a, b string) []Edit {
+	var d differ
+	d.compare(splitLines(a), splitLines(b))
+	return d.edits
+}
+
+// differ collects the edits of a comparison in order
+type differ struct {
+	edits []Edit
+}
+
+func (d *differ) add(op Op, lines []string) {
+	for _, line := range lines {
+		d.edits = append(d.edits, Edit{op, line})
+	}
+}
+
+// compare adds the edits turning x into y. The common prefix and suffix are
+// split off, the rest is divided at the middle of a shortest edit path and
+// compared recursively.
+func (d *differ) compare(x, y []string) {
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	d.add(Equal, x[:prefix])
+	x, y = x[prefix:], y[prefix:]
+	suffix := 0
+	for suffix < len(x) && suffix < len(y) && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	common := x[len(x)-suffix:]
+	x, y = x[:len(x)-suffix], y[:len(y)-suffix]
+
+	if len(x) == 0 || len(y) == 0 {
+		d.add(Delete, x)
+		d.add(Insert, y)
+	} else if i, j, ok := middle(x, y); ok {
+		d.compare(x[:i], y[:j])
+		d.compare(x[i:], y[j:])
+	} else {
+		d.add(Delete, x)
+		d.add(Insert, y)
+	}
+	d.add(Equal, common)
+}
+
+// middle searches a shortest edit path from both ends at the same time and
+// returns the point where they meet. x and y have to differ in their first
+// and last lines, so that the point is never at an end. Only the furthest
+// points on each diagonal are kept, so the memory grows linearly.
+func middle(x, y []string) (int, int, bool) {
+	n, m := len(x), len(y)
+	maxD := (n + m + 1) / 2
+	// forward and backward are indexed by the diagonals k from -maxD to
+	// maxD and hold how far the paths got on them, or -1
+	offset := maxD
+	forward, backward := make([]int, 2*maxD+2), make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+	delta := n - m
+	// With an odd delta the paths meet while going forward
+	odd := delta%2 != 0
+	// Diagonals which left the grid are not searched anymore
+	startF, endF, startB, endB := 0, 0, 0, 0
+	for d := 0; d < maxD; d++ {
+		for k := -d + startF; k <= d-endF; k += 2 {
+			var i int
+			if k == -d || k != d && forward[offset+k-1] < forward[offset+k+1] {
+				i = forward[offset+k+1]
+			} else {
+				i = forward[offset+k-1] + 1
+			}
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+			forward[offset+k] = i
+			switch {
+			case i > n:
+				endF += 2
+			case j > m:
+				startF += 2
+			case odd:
+				kb := offset + delta - k
+				if kb >= 0 && kb < len(backward) && backward[kb] != -1 && i >= n-backward[kb] {
+					return i, j, true
+				}
+			}
+		}
+		for k := -d + startB; k <= d-endB; k += 2 {
+			var i int
+			if k == -d || k != d && backward[offset+k-1] < backward[offset+k+1] {
+				i = backward[offset+k+1]
+			} else {
+				i = backward[offset+k-1] + 1
+			}
+			j := i - k
+			for i < n && j < m && x[n-i-1] == y[m-j-1] {
+				i++
+				j++
+			}
+			backward[offset+k] = i
+			switch {
+			case i > n:
+				endB += 2
+			case j > m:
+				startB += 2
+			case !odd:
+				kf := offset + delta - k
+				if kf >= 0 && kf < len(forward) && forward[kf] != -1 {
+					fi := forward[kf]
+					if fi >= n-i {
+						return fi, fi - (kf - offset), true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// Unified returns the differences of a and b as unified diff with the lines
+// of context around each change, or an empty string if they are equal
+func Unified(nameA, nameB, a, b string, context int) string {
+	edits := Lines(a, b)
+	var s strings.Builder
+	// lineA and lineB are the line numbers before each edit
+	lineA, lineB := make([]int, len(edits)+1), make([]int, len(edits)+1)
+	for i, e := range edits {
+		lineA[i+1], lineB[i+1] = lineA[i], lineB[i]
+		if e.Op != Insert {
+			lineA[i+1]++
+		}
+		if e.Op != Delete {
+			lineB[i+1]++
+		}
+	}
+	for start := 0; start < len(edits); {
+		// Find the next change and the end of its hunk, merging changes
+		// which are closer than twice the context
+		first := start
+		for first < len(edits) && edits[first].Op == Equal {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		end := first
+		for i := first; i < len(edits) && i-end <= 2*context; i++ {
+			if edits[i].Op != Equal {
+				end = i + 1
+			}
+		}
+		from, to := max(first-context, 0), min(end+context, len(edits))
+		if s.Len() == 0 {
+			fmt.Fprintf(&s, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		fmt.Fprintf(&s, "@@ -%s +%s @@\n",
+			hunkRange(lineA[from], lineA[to]-lineA[from]), hunkRange(lineB[from], lineB[to]-lineB[from]))
+		for _, e := range edits[from:to] {
+			s.WriteString([]string{" ", "+", "-"}[e.Op] + e.Line + "\n")
+		}
+		start = to
+	}
+	return s.String()
+}
+
+// hunkRange formats the start and length of a hunk, where empty ranges start
+// at the line before them
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
@@ -0,0 +1,112 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// lcs returns the length of the longest common subsequence of the lines
+func lcs(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+	return table[0][0]
+}
+
+// apply returns both texts of an edit script
+func apply(edits []Edit) (string, string) {
+	var a, b strings.Builder
+	for _, e := range edits {
+		if e.Op != Insert {
+			a.WriteString(e.Line + "\n")
+		}
+		if e.Op != Delete {
+			b.WriteString(e.Line + "\n")
+		}
+	}
+	return a.String(), b.String()
+}
+
+func randomText(r *rand.Rand, lines int) string {
+	var s strings.Builder
+	for i := 0; i < lines; i++ {
+		s.WriteString(string(rune('a'+r.Intn(3))) + "\n")
+	}
+	return s.String()
+}
+
+func TestLinesIsShortest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 2000; n++ {
+		a, b := randomText(r, r.Intn(12)), randomText(r, r.Intn(12))
+		edits := Lines(a, b)
+		if gotA, gotB := apply(edits); gotA != a || gotB != b {
+			t.Fatalf("Lines(%q, %q) turns %q into %q", a, b, gotA, gotB)
+		}
+		equal := 0
+		for _, e := range edits {
+			if e.Op == Equal {
+				equal++
+			}
+		}
+		if want := lcs(splitLines(a), splitLines(b)); equal != want {
+			t.Fatalf("Lines(%q, %q) keeps %d lines, the longest common subsequence has %d", a, b, equal, want)
+		}
+	}
+}
+
+func TestLinesUnrelatedTexts(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < 5000; i++ {
+		a.WriteString("a" + strings.Repeat("x", i%7) + "\n")
+		b.WriteString("b" + strings.Repeat("y", i%5) + "\n")
+	}
+	edits := Lines(a.String(), b.String())
+	if len(edits) != 10000 {
+		t.Fatalf("got %d edits, want 10000", len(edits))
+	}
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", 3, ""},
+		{"empty to text", "", "a\n", 3, "--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n"},
+		{
+			"change in the middle",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			"1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			3,
+			"--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			"separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			"one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			1,
+			"--- a\n+++ b\n@@ -1,2 +1,2 @@\n-1\n+one\n 2\n@@ -9,2 +9,2 @@\n 9\n-10\n+ten\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a", "b", tt.a, tt.b, tt.context); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package jenkins
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type Artifact struct {
+	FileName     string `json:"fileName"`
+	RelativePath string `json:"relativePath"`
+}
+
+// GetArtifacts returns the artifacts archived by the build at buildUrl
+func (j *Jenkins) GetArtifacts(buildUrl string) ([]Artifact, error) {
+	var build struct {
+		Artifacts []Artifact `json:"artifacts"`
+	}
+	tree := url.QueryEscape("artifacts[fileName,relativePath]")
+	if err := j.getJSON(strings.TrimSuffix(buildUrl, "/")+"/api/json?tree="+tree, &build); err != nil {
+		return nil, err
+	}
+	return build.Artifacts, nil
+}
+
+// ArtifactUrl returns the download URL of an artifact of the build
+func ArtifactUrl(buildUrl, relativePath string) string {
+	parts := strings.Split(relativePath, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.TrimSuffix(buildUrl, "/") + "/artifact/" + strings.Join(parts, "/")
+}
+
+// ArchiveUrl returns the URL of a zip file containing all artifacts of the build
+func ArchiveUrl(buildUrl string) string {
+	return strings.TrimSuffix(buildUrl, "/") + "/artifact/*zip*/archive.zip"
+}
+
+// DownloadFile downloads fileUrl to dest. The data is written to dest.part
+// first and moved into place once complete, so an interrupted download is
+// resumed from where it stopped. The ETag or Last-Modified date of the file
+// is kept in dest.part.validator and sent with If-Range, so a partial file
+// of another version of the file is downloaded again from the start. The
+// progress callback, if given, is called with the number of bytes written so
+// far and the total size, which is -1 when the server does not report it.
+func (j *Jenkins) DownloadFile(fileUrl, dest string, progress func(written, total int64)) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	partial := dest + ".part"
+	validatorFile := partial + ".validator"
+	offset, validator := partialDownload(partial, validatorFile)
+
+	resp, err := j.getFrom(fileUrl, offset, validator)
+	if err != nil {
+		return err
+	}
+	defer func() { resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		// The file changed or the server ignored the range
+		offset = 0
+	case resp.StatusCode == http.StatusPartialContent && contentRangeStart(resp) == offset:
+	case resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file does not fit the file on the server, start over
+		resp.Body.Close()
+		offset = 0
+		if resp, err = j.getFrom(fileUrl, 0, ""); err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("GET %s: %s", fileUrl, resp.Status)
+		}
+	default:
+		return fmt.Errorf("GET %s: %s", fileUrl, resp.Status)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if offset == 0 {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		// Without a validator the download cannot be resumed safely
+		if v := responseValidator(resp); v != "" {
+			err = os.WriteFile(validatorFile, []byte(v), 0o644)
+		} else {
+			err = os.Remove(validatorFile)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	file, err := os.OpenFile(partial, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	w := &progressWriter{written: offset, total: total, progress: progress}
+	_, err = io.Copy(io.MultiWriter(file, w), resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(partial, dest); err != nil {
+		return err
+	}
+	if err := os.Remove(validatorFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// getFrom requests the file from the offset on, if it still matches the
+// validator. Without an offset or a validator the whole file is requested.
+func (j *Jenkins) getFrom(fileUrl string, offset int64, validator string) (*http.Response, error) {
+	req, err := j.newRequest("GET", fileUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 && validator != "" {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header.Set("If-Range", validator)
+	}
+	return j.do(req)
+}
+
+// partialDownload returns the size of the partial file and the validator of
+// the file it was downloaded from. Partial files without a validator cannot
+// be resumed, so their size is 0.
+func partialDownload(partial, validatorFile string) (int64, string) {
+	info, err := os.Stat(partial)
+	if err != nil {
+		return 0, ""
+	}
+	validator, err := os.ReadFile(validatorFile)
+	if err != nil || len(validator) == 0 {
+		return 0, ""
+	}
+	return info.Size(), string(validator)
+}
+
+// responseValidator returns the strong ETag of the response or its
+// Last-Modified date, which are the validators If-Range accepts
+func responseValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// contentRangeStart returns the offset of the data of a partial response,
+// or -1 without a valid Content-Range
+func contentRangeStart(resp *http.Response) int64 {
+	var start, end int64
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d", &start, &end); err != nil {
+		return -1
+	}
+	return start
+}
+
+// progressWriter reports the number of bytes written through it
+type progressWriter struct {
+	written  int64
+	total    int64
+	progress func(written, total int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	if w.progress != nil {
+		w.progress(w.written, w.total)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,77 @@
+package jenkins_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"jcli/jenkins"
+)
+
+// countingWriter counts the bytes of the response body
+type countingWriter struct {
+	http.ResponseWriter
+	written int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.written += n
+	return n, err
+}
+
+func TestDownloadFile(t *testing.T) {
+	const content = "0123456789"
+	// served are the bytes sent by the last response
+	var served int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cw := &countingWriter{ResponseWriter: w}
+		cw.Header().Set("ETag", `"v1"`)
+		http.ServeContent(cw, r, "app.jar", time.Time{}, strings.NewReader(content))
+		served = cw.written
+	}))
+	defer srv.Close()
+	j := jenkins.NewJenkins(srv.URL, "alice", "secret")
+
+	for _, test := range []struct {
+		name, partial, validator string
+		resumed                  bool
+	}{
+		{"new", "", "", false},
+		{"resumed", "01234", `"v1"`, true},
+		{"changed file", "abcde", `"v0"`, false},
+		{"no validator", "abcde", "", false},
+		{"longer than the file", "0123456789ab", `"v1"`, false},
+	} {
+		dest := filepath.Join(t.TempDir(), "app.jar")
+		if test.partial != "" {
+			if err := os.WriteFile(dest+".part", []byte(test.partial), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if test.validator != "" {
+			if err := os.WriteFile(dest+".part.validator", []byte(test.validator), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := j.DownloadFile(srv.URL+"/artifact/app.jar", dest, nil); err != nil {
+			t.Errorf("%s: DownloadFile() = %v", test.name, err)
+			continue
+		}
+		if data, err := os.ReadFile(dest); err != nil || string(data) != content {
+			t.Errorf("%s: downloaded %q, %v, want %q", test.name, data, err, content)
+		}
+		if resumed := served < len(content); resumed != test.resumed {
+			t.Errorf("%s: the last response sent %d bytes, want resumed %v", test.name, served, test.resumed)
+		}
+		for _, file := range []string{dest + ".part", dest + ".part.validator"} {
+			if _, err := os.Stat(file); !os.IsNotExist(err) {
+				t.Errorf("%s: %s was not removed", test.name, filepath.Base(file))
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package jenkins
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// buildTree selects the build fields jcli needs from the REST API
+const buildTree = "number,url,result,building,duration,estimatedDuration,timestamp," +
+	"previousBuild[number,url]," +
+	"actions[causes[_class,shortDescription,originalNumber]]," +
+	"changeSet[items[commitId,msg,author[fullName]]]," +
+	"changeSets[items[commitId,msg,author[fullName]]]"
+
+type Job struct {
+	Name               string  `json:"name"`
+	FullName           string  `json:"fullName"`
+	Url                string  `json:"url"`
+	Color              string  `json:"color"`
+	Buildable          bool    `json:"buildable"`
+	InQueue            bool    `json:"inQueue"`
+	LastBuild          *Build  `json:"lastBuild"`
+	LastCompletedBuild *Build  `json:"lastCompletedBuild"`
+	Builds             []Build `json:"builds"`
+}
+
+type Build struct {
+	Number            int         `json:"number"`
+	Url               string      `json:"url"`
+	Result            string      `json:"result"`
+	Building          bool        `json:"building"`
+	Duration          int64       `json:"duration"`
+	EstimatedDuration int64       `json:"estimatedDuration"`
+	Timestamp         int64       `json:"timestamp"`
+	PreviousBuild     *Build      `json:"previousBuild"`
+	Actions           []Action    `json:"actions"`
+	ChangeSet         ChangeSet   `json:"changeSet"`
+	ChangeSets        []ChangeSet `json:"changeSets"`
+}
+
+type Action struct {
+	Causes []Cause `json:"causes"`
+}
+
+type Cause struct {
+	Class            string `json:"_class"`
+	ShortDescription string `json:"shortDescription"`
+	// OriginalNumber is the replayed build of replays
+	OriginalNumber int `json:"originalNumber"`
+}
+
+type ChangeSet struct {
+	Items []Change `json:"items"`
+}
+
+type Change struct {
+	CommitId string `json:"commitId"`
+	Msg      string `json:"msg"`
+	Author   struct {
+		FullName string `json:"fullName"`
+	} `json:"author"`
+}
+
+// Status returns the result of the build, or RUNNING while it is building
+func (b Build) Status() string {
+	if b.Building {
+		return "RUNNING"
+	}
+	if b.Result == "" {
+		return "UNKNOWN"
+	}
+	return b.Result
+}
+
+// StartTime returns the time the build started
+func (b Build) StartTime() time.Time {
+	return time.UnixMilli(b.Timestamp)
+}
+
+// Elapsed returns the duration of a finished build, or the time passed
+// since the start for a running build
+func (b Build) Elapsed() time.Duration {
+	if b.Building {
+		return time.Since(b.StartTime()).Truncate(time.Second)
+	}
+	return (time.Duration(b.Duration) * time.Millisecond).Truncate(time.Second)
+}
+
+// Causes returns the short descriptions of what triggered the build
+func (b Build) Causes() []string {
+	var causes []string
+	for _, action := range b.Actions {
+		for _, cause := range action.Causes {
+			causes = append(causes, cause.ShortDescription)
+		}
+	}
+	return causes
+}
+
+// Commits returns the commit SHAs of all change sets of the build.
+// Pipeline jobs report changeSets, freestyle jobs a single changeSet.
+func (b Build) Commits() []string {
+	var commits []string
+	changeSets := append([]ChangeSet{b.ChangeSet}, b.ChangeSets...)
+	for _, changeSet := range changeSets {
+		for _, item := range changeSet.Items {
+			commits = append(commits, item.CommitId)
+		}
+	}
+	return commits
+}
+
+// GetJob returns the job including its most recent builds.
+// A limit of zero or less returns every build Jenkins still keeps.
+func (j *Jenkins) GetJob(jobName string, limit int) (*Job, error) {
+	builds := "builds[" + buildTree + "]"
+	if limit > 0 {
+		builds += fmt.Sprintf("{0,%d}", limit)
+	}
+	tree := strings.Join([]string{
+		"name", "fullName", "url", "color", "buildable", "inQueue",
+		"lastBuild[" + buildTree + "]", "lastCompletedBuild[number,url,result]", builds,
+	}, ",")
+	var job Job
+	if err := j.getJSON(j.JobUrl(jobName)+"/api/json?tree="+url.QueryEscape(tree), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetBuilds returns the most recent builds of a job, newest first
+func (j *Jenkins) GetBuilds(jobName string, limit int) ([]Build, error) {
+	job, err := j.GetJob(jobName, limit)
+	if err != nil {
+		return nil, err
+	}
+	return job.Builds, nil
+}
+
+// GetBuild returns the build found at buildUrl
+func (j *Jenkins) GetBuild(buildUrl string) (*Build, error) {
+	var build Build
+	err := j.getJSON(strings.TrimSuffix(buildUrl, "/")+"/api/json?tree="+url.QueryEscape(buildTree), &build)
+	if err != nil {
+		return nil, err
+	}
+	return &build, nil
+}
+
+// AbortBuild stops the running build at buildUrl
+func (j *Jenkins) AbortBuild(buildUrl string) error {
+	return j.post(strings.TrimSuffix(buildUrl, "/") + "/stop")
+}
@@ -0,0 +1,403 @@
+// Package cassette records the HTTP interactions of the jenkins client with a
+// real controller and replays them in tests.
+//
+// A recording is a JSON file of request and response pairs. Credentials are
+// scrubbed before anything is written: the Authorization, Cookie and crumb
+// headers are dropped, and the address of the controller and the name of the
+// user are replaced with placeholders, so that the same cassette replays
+// against any address and for any user. Bodies which are not text are saved
+// base64 encoded.
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"jcli/jenkins"
+)
+
+// Address replaces the address of the controller in recorded URLs, headers
+// and bodies
+const Address = "http://jenkins.example"
+
+// User replaces the name of the user in recorded URLs, headers and bodies
+const User = "jcli-user"
+
+// base64Encoding marks bodies which are saved base64 encoded
+const base64Encoding = "base64"
+
+// startedByRegexp matches the user in the causes of builds and queue items,
+// which Jenkins gives with the full name of the user
+var startedByRegexp = regexp.MustCompile(`(Started by user |Replayed by |Aborted by |"userName":")[^"\n<\\]+`)
+
+// userLinkRegexp matches the full name in the links to users of the HTML
+// console log
+var userLinkRegexp = regexp.MustCompile(`(<a href=['"][^'"]*/user/[^'"]*['"][^>]*>)[^<]+`)
+
+// Mode is whether the recorder records or replays
+type Mode int
+
+const (
+	// Record sends the requests to the controller and saves them
+	Record Mode = iota
+	// Replay answers the requests from the cassette
+	Replay
+)
+
+// scrubbedHeaders are never written to a cassette
+var scrubbedHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"Jenkins-Crumb",
+	"X-Jenkins-Session",
+	"X-Instance-Identity",
+}
+
+// ErrNotRecorded is returned for requests the cassette has no response for
+var ErrNotRecorded = errors.New("request not recorded")
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string `json:"method"`
+	// Url is the path and query of the request
+	Url  string `json:"url"`
+	Body string `json:"body,omitempty"`
+	// Encoding is base64 for bodies which are not text
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type Response struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body"`
+	// Encoding is base64 for bodies which are not text
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Cassette is the content of a recording
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Jenkins returns the version of the controller the cassette was recorded
+// against
+func (c Cassette) Jenkins() string {
+	for _, interaction := range c.Interactions {
+		if version := interaction.Response.Headers.Get("X-Jenkins"); version != "" {
+			return version
+		}
+	}
+	return ""
+}
+
+// cassetteEnd follows the last interaction in a cassette file
+const cassetteEnd = "\n  ]\n}\n"
+
+// Recorder is an http.RoundTripper which records interactions to a file or
+// replays them from it
+type Recorder struct {
+	// Transport sends the requests while recording. Nil uses the default
+	// transport.
+	Transport http.RoundTripper
+
+	mode     Mode
+	address  string
+	user     *regexp.Regexp
+	userName string
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+	// out is the cassette file while recording, end is the offset of the
+	// end of the last interaction in it
+	out *os.File
+	end int64
+}
+
+// New creates a recorder for the cassette file. Recording creates an empty
+// cassette file, replaying loads the file.
+func New(file string, mode Mode) (*Recorder, error) {
+	r := &Recorder{mode: mode}
+	if mode == Record {
+		out, err := os.Create(file)
+		if err != nil {
+			return nil, err
+		}
+		start := "{\n  \"interactions\": ["
+		if _, err := out.WriteString(start + cassetteEnd); err != nil {
+			out.Close()
+			return nil, err
+		}
+		r.out, r.end = out, int64(len(start))
+		return r, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Wrap makes the client send its requests through the recorder. While
+// recording, the transport of the client sends them on.
+func (r *Recorder) Wrap(j *jenkins.Jenkins) {
+	r.address = strings.TrimSuffix(j.Address, "/")
+	r.userName = j.User
+	if j.User != "" {
+		r.user = userRegexp(j.User)
+	}
+	client := http.Client{}
+	if j.Client != nil {
+		client = *j.Client
+	}
+	if r.Transport == nil {
+		r.Transport = client.Transport
+	}
+	client.Transport = r
+	j.Client = &client
+}
+
+// Cassette returns the interactions recorded or loaded so far
+func (r *Recorder) Cassette() Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.cassette
+	c.Interactions = append([]Interaction(nil), c.Interactions...)
+	return c
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	recorded := Request{
+		Method: req.Method,
+		Url:    r.scrub(req.URL.RequestURI()),
+	}
+	recorded.Body, recorded.Encoding = r.scrubBody(body)
+	if r.mode == Replay {
+		return r.replay(req, recorded)
+	}
+	return r.record(req, recorded)
+}
+
+func (r *Recorder) record(req *http.Request, recorded Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	headers := http.Header{}
+	for key, values := range resp.Header {
+		for _, value := range values {
+			headers.Add(key, r.scrub(value))
+		}
+	}
+	for _, key := range scrubbedHeaders {
+		headers.Del(key)
+	}
+	recordedBody, encoding := r.scrubBody(body)
+	if strings.HasPrefix(req.URL.Path, "/crumbIssuer/") {
+		recordedBody = scrubCrumb(recordedBody)
+	}
+	interaction := Interaction{
+		Request:  recorded,
+		Response: Response{Status: resp.StatusCode, Headers: headers, Body: recordedBody, Encoding: encoding},
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	// Save every request, commands may exit without returning
+	if err := r.append(interaction); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// replay answers with the first unused interaction for the method and URL.
+// Once all of them were used the last one is repeated, so that polling
+// loops may poll more often than while recording.
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := -1
+	for i, interaction := range r.cassette.Interactions {
+		if interaction.Request.Method != recorded.Method || interaction.Request.Url != recorded.Url {
+			continue
+		}
+		last = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if last < 0 {
+		return nil, fmt.Errorf("%s %s: %w", recorded.Method, recorded.Url, ErrNotRecorded)
+	}
+	r.used[last] = true
+	recordedResp := r.cassette.Interactions[last].Response
+	headers := http.Header{}
+	for key, values := range recordedResp.Headers {
+		for _, value := range values {
+			headers.Add(key, r.unscrub(value))
+		}
+	}
+	body := recordedResp.Body
+	if recordedResp.Encoding == base64Encoding {
+		data, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", recorded.Method, recorded.Url, err)
+		}
+		body = string(data)
+	} else {
+		body = r.unscrub(body)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recordedResp.Status, http.StatusText(recordedResp.Status)),
+		StatusCode:    recordedResp.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        headers,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// append adds the interaction to the end of the cassette file, which stays
+// a complete cassette
+func (r *Recorder) append(interaction Interaction) error {
+	// Keep the XML and HTML in bodies readable
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("    ", "  ")
+	if err := encoder.Encode(interaction); err != nil {
+		return err
+	}
+	separator := ","
+	if len(r.cassette.Interactions) == 1 {
+		separator = ""
+	}
+	entry := separator + "\n    " + strings.TrimSuffix(data.String(), "\n")
+	if _, err := r.out.WriteAt([]byte(entry+cassetteEnd), r.end); err != nil {
+		return err
+	}
+	r.end += int64(len(entry))
+	return nil
+}
+
+// Close closes the cassette file while recording. The file is complete
+// without closing it, too.
+func (r *Recorder) Close() error {
+	if r.out == nil {
+		return nil
+	}
+	return r.out.Close()
+}
+
+// userRegexp matches the user name as a whole word, as is and escaped in
+// URLs
+func userRegexp(user string) *regexp.Regexp {
+	var names []string
+	for _, name := range []string{user, url.PathEscape(user), url.QueryEscape(user)} {
+		pattern := regexp.QuoteMeta(name)
+		// Word boundaries only exist next to word characters
+		if isWordChar(name[0]) {
+			pattern = `\b` + pattern
+		}
+		if isWordChar(name[len(name)-1]) {
+			pattern += `\b`
+		}
+		names = append(names, pattern)
+	}
+	return regexp.MustCompile(strings.Join(names, "|"))
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// scrub replaces the address of the controller and the user with the
+// placeholders
+func (r *Recorder) scrub(s string) string {
+	if r.address != "" {
+		s = strings.ReplaceAll(s, r.address, Address)
+	}
+	if r.user != nil {
+		s = r.user.ReplaceAllLiteralString(s, User)
+	}
+	s = startedByRegexp.ReplaceAllString(s, "${1}"+User)
+	return userLinkRegexp.ReplaceAllString(s, "${1}"+User)
+}
+
+// scrubBody scrubs a text body and returns the encoding it is saved with.
+// Other bodies are saved base64 encoded, scrubbing would corrupt them.
+func (r *Recorder) scrubBody(body []byte) (string, string) {
+	if !utf8.Valid(body) {
+		return base64.StdEncoding.EncodeToString(body), base64Encoding
+	}
+	return r.scrub(string(body)), ""
+}
+
+// scrubCrumb replaces the crumb in a response of the crumb issuer
+func scrubCrumb(body string) string {
+	var crumb map[string]any
+	if err := json.Unmarshal([]byte(body), &crumb); err != nil {
+		return body
+	}
+	crumb["crumb"] = "scrubbed"
+	data, err := json.Marshal(crumb)
+	if err != nil {
+		return body
+	}
+	return string(data)
+}
+
+// unscrub puts the address and user of the client in place of the
+// placeholders
+func (r *Recorder) unscrub(s string) string {
+	if r.address != "" {
+		s = strings.ReplaceAll(s, Address, r.address)
+	}
+	if r.userName != "" {
+		s = strings.ReplaceAll(s, User, r.userName)
+	}
+	return s
+}
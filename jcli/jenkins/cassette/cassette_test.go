@@ -0,0 +1,234 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jcli/jenkins"
+	"jcli/jenkins/jenkinstest"
+)
+
+const buildLog = "Started by user alice\n[Pipeline] echo\nhello\n[Pipeline] End of Pipeline\nFinished: SUCCESS\n"
+
+// newServer starts a fake server with CSRF protection, the user alice and
+// the pipeline job team/app
+func newServer(t *testing.T) *jenkinstest.Server {
+	t.Helper()
+	s := jenkinstest.NewServer()
+	t.Cleanup(s.Close)
+	s.User, s.APIKey = "alice", "secret"
+	s.RequireCrumb = true
+	s.AddJob("team/app", jenkins.PipelineJobConfig)
+	s.SetBuildLog("team/app", buildLog, "SUCCESS")
+	return s
+}
+
+// updateAndBuild updates the pipeline script of team/app, builds it and
+// returns the log of the build, like jcli update does
+func updateAndBuild(j *jenkins.Jenkins) (string, error) {
+	config, err := j.GetJobConfig("team/app")
+	if err != nil {
+		return "", err
+	}
+	updated, err := jenkins.ReplacePipelineScript(config, "pipeline {\n  agent any\n  stages {\n    stage('Hello') {\n      steps {\n        echo 'hello'\n      }\n    }\n  }\n}")
+	if err != nil {
+		return "", err
+	}
+	if err := j.UpdateJobConfig("team/app", updated); err != nil {
+		return "", err
+	}
+	buildUrl, err := j.TriggerBuild("team/app")
+	if err != nil {
+		return "", err
+	}
+	var log strings.Builder
+	var start int64
+	for {
+		text, next, more, err := j.GetProgressiveText(buildUrl, start)
+		if err != nil {
+			return "", err
+		}
+		log.WriteString(text)
+		start = next
+		if !more {
+			return log.String(), nil
+		}
+	}
+}
+
+// readCassette parses the cassette file
+func readCassette(t *testing.T, file string) Cassette {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("%s: %v\n%s", file, err, data)
+	}
+	return c
+}
+
+func TestRecord(t *testing.T) {
+	s := newServer(t)
+	file := filepath.Join(t.TempDir(), "cassette.json")
+	r, err := New(file, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	j := s.Client()
+	r.Wrap(j)
+
+	// The file is a complete cassette after every request
+	if c := readCassette(t, file); len(c.Interactions) != 0 {
+		t.Errorf("new cassette has %d interactions", len(c.Interactions))
+	}
+	if _, err := j.GetJobConfig("team/app"); err != nil {
+		t.Fatal(err)
+	}
+	if c := readCassette(t, file); len(c.Interactions) != 1 {
+		t.Errorf("cassette has %d interactions after one request", len(c.Interactions))
+	}
+
+	log, err := updateAndBuild(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log != buildLog {
+		t.Errorf("log = %q, want %q", log, buildLog)
+	}
+	c := readCassette(t, file)
+	if want := len(s.Requests()); len(c.Interactions) != want {
+		t.Errorf("cassette has %d interactions, want %d", len(c.Interactions), want)
+	}
+	if !sameCassette(c, r.Cassette()) {
+		t.Error("the cassette file differs from the recorded cassette")
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"alice", "secret", s.URL, jenkinstest.Crumb, "Authorization"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("cassette contains %q", secret)
+		}
+	}
+	if !bytes.Contains(data, []byte("Started by user "+User)) {
+		t.Errorf("cassette does not contain the user placeholder")
+	}
+}
+
+// sameCassette compares cassettes by their JSON, which is what is saved
+func sameCassette(a, b Cassette) bool {
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(dataA, dataB)
+}
+
+func TestReplay(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cassette.json")
+	r, err := New(file, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	recording := newServer(t).Client()
+	r.Wrap(recording)
+	if _, err := updateAndBuild(recording); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = New(file, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing listens on the address, all responses come from the cassette
+	j := jenkins.NewJenkins("http://127.0.0.1:9", "bob", "key")
+	r.Wrap(j)
+	log, err := updateAndBuild(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(buildLog, "alice", "bob", 1); log != want {
+		t.Errorf("log = %q, want %q", log, want)
+	}
+	if r.Cassette().Jenkins() != "" {
+		t.Errorf("Jenkins() = %q, the fake server sends no version", r.Cassette().Jenkins())
+	}
+
+	// Without retrying, which would wait for the request in vain
+	j.Retry = jenkins.RetryPolicy{}
+	if _, err := j.GetBuilds("other", 1); err == nil || !strings.Contains(err.Error(), ErrNotRecorded.Error()) {
+		t.Errorf("request which was not recorded: %v, want %v", err, ErrNotRecorded)
+	}
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestBinaryBody(t *testing.T) {
+	binary := []byte{0x1f, 0x8b, 0x08, 0x00, 0xff, 0xfe, 'a', 'l', 'i', 'c', 'e'}
+	file := filepath.Join(t.TempDir(), "cassette.json")
+	r, err := New(file, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       io.NopCloser(bytes.NewReader(binary)),
+		}, nil
+	})
+	j := jenkins.NewJenkins("http://ci.local", "alice", "key")
+	r.Wrap(j)
+	if config, err := j.GetJobConfig("app"); err != nil || config != string(binary) {
+		t.Fatalf("GetJobConfig() while recording = %q, %v", config, err)
+	}
+	if c := readCassette(t, file); c.Interactions[0].Response.Encoding != base64Encoding {
+		t.Errorf("binary body saved with encoding %q", c.Interactions[0].Response.Encoding)
+	}
+
+	r, err = New(file, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j = jenkins.NewJenkins("http://ci.local", "bob", "key")
+	r.Wrap(j)
+	if config, err := j.GetJobConfig("app"); err != nil || config != string(binary) {
+		t.Errorf("GetJobConfig() while replaying = %q, %v, want %q", config, err, binary)
+	}
+}
+
+func TestScrub(t *testing.T) {
+	for _, test := range []struct {
+		user, in, want string
+	}{
+		{"ci", "http://ci.local/user/ci/api/json", Address + "/user/" + User + "/api/json"},
+		{"ci", "circle ci", "circle " + User},
+		{"ci", `{"shortDescription":"Started by user CI Bot"}`, `{"shortDescription":"Started by user ` + User + `"}`},
+		{"ci", "Replayed by CI Bot\nAborted by ci\n", "Replayed by " + User + "\nAborted by " + User + "\n"},
+		{"ci", `"userId":"ci","userName":"CI Bot"`, `"userId":"` + User + `","userName":"` + User + `"`},
+		{"ci", "Started by user <a href='/user/ci' class='model-link'>CI Bot</a>", "Started by user <a href='/user/" + User + "' class='model-link'>" + User + "</a>"},
+		{"alice@example.com", "/user/alice%40example.com/ alice@example.com", "/user/" + User + "/ " + User},
+		{".hidden", "x.hidden .hidden", "x" + User + " " + User},
+	} {
+		r := &Recorder{address: "http://ci.local", user: userRegexp(test.user), userName: test.user}
+		if got := r.scrub(test.in); got != test.want {
+			t.Errorf("scrub(%q) for %s = %q, want %q", test.in, test.user, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package cassette
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"jcli/jenkins"
+)
+
+// The cassettes in testdata/lts-*.json hold the interactions of jcli update
+// and build with the 2.426.3 and 2.440.3 LTS controllers. They were written
+// by hand after the responses of these releases and are replaced by
+// recordings with
+//
+//	go test ./jenkins/cassette -run TestRecordLTS -jenkins http://localhost:8080 -jenkins-user alice -jenkins-key ...
+//
+// which writes testdata/lts-<version>.json. The controller needs the
+// pipeline job team/app with concurrent builds disabled and an agent
+// labeled linux.
+var (
+	controller     = flag.String("jenkins", "", "record testdata/lts-<version>.json against the controller at this address")
+	controllerUser = flag.String("jenkins-user", "", "user to record the LTS cassette with")
+	controllerKey  = flag.String("jenkins-key", "", "API key of the user to record the LTS cassette with")
+)
+
+// ltsScript is the pipeline script the LTS cassettes set on team/app, with
+// characters which have to be escaped in the config.xml
+const ltsScript = "pipeline {\n  agent { label 'linux' }\n  stages {\n    stage('Build') {\n      steps {\n        echo \"building ${env.JOB_NAME} & <friends>\"\n        sleep 20\n        echo 'done'\n      }\n    }\n  }\n}\n"
+
+// scriptRegexp matches the pipeline script element of a config.xml
+var scriptRegexp = regexp.MustCompile(`(?s)<script>.*</script>`)
+
+// replaceScript sets ltsScript on team/app like jcli update does. It returns
+// the config before, the config sent and the config the controller has
+// afterwards.
+func replaceScript(j *jenkins.Jenkins) (before, sent, after string, err error) {
+	if before, err = j.GetJobConfig("team/app"); err != nil {
+		return
+	}
+	if sent, err = jenkins.ReplacePipelineScript(before, ltsScript); err != nil {
+		return
+	}
+	if err = j.UpdateJobConfig("team/app", sent); err != nil {
+		return
+	}
+	after, err = j.GetJobConfig("team/app")
+	return
+}
+
+// waitForBuild builds team/app, polls the queue until the build started and
+// returns the URL of the build
+func waitForBuild(j *jenkins.Jenkins, interval time.Duration) (string, error) {
+	queueUrl, err := j.QueueBuild("team/app")
+	if err != nil {
+		return "", err
+	}
+	for polls := 0; polls < 60; polls++ {
+		buildUrl, inQueue, err := j.CheckInQueue(queueUrl)
+		if err != nil {
+			return "", err
+		}
+		if !inQueue {
+			if buildUrl == "" {
+				return "", fmt.Errorf("the build was cancelled in the queue")
+			}
+			return buildUrl, nil
+		}
+		time.Sleep(interval)
+	}
+	return "", fmt.Errorf("the build did not start")
+}
+
+// cancelBuild queues another build of team/app, which is blocked while the
+// first one runs, and cancels it. It returns the item before cancelling
+// and the state of the queue item afterwards.
+func cancelBuild(j *jenkins.Jenkins) (item *jenkins.QueueItem, buildUrl string, inQueue bool, err error) {
+	queueUrl, err := j.QueueBuild("team/app")
+	if err != nil {
+		return
+	}
+	if item, err = j.GetQueueItem(queueUrl); err != nil {
+		return
+	}
+	if err = j.CancelQueueItem(item.Id); err != nil {
+		return
+	}
+	buildUrl, inQueue, err = j.CheckInQueue(queueUrl)
+	return
+}
+
+// logChunk is the answer to one poll of the console log
+type logChunk struct {
+	start, next int64
+	text        string
+	more        bool
+}
+
+// followLog polls the console log of the build until it finished
+func followLog(j *jenkins.Jenkins, buildUrl string, interval time.Duration) ([]logChunk, error) {
+	var chunks []logChunk
+	var start int64
+	for polls := 0; polls < 120; polls++ {
+		text, next, more, err := j.GetProgressiveText(buildUrl, start)
+		if err != nil {
+			return chunks, err
+		}
+		chunks = append(chunks, logChunk{start: start, next: next, text: text, more: more})
+		if !more {
+			return chunks, nil
+		}
+		start = next
+		time.Sleep(interval)
+	}
+	return chunks, fmt.Errorf("the build did not finish")
+}
+
+func TestRecordLTS(t *testing.T) {
+	if *controller == "" {
+		t.Skip("no controller to record against, set -jenkins")
+	}
+	file := filepath.Join(t.TempDir(), "lts.json")
+	r, err := New(file, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	j := jenkins.NewJenkins(*controller, *controllerUser, *controllerKey)
+	r.Wrap(j)
+
+	if _, _, _, err := replaceScript(j); err != nil {
+		t.Fatal(err)
+	}
+	buildUrl, err := waitForBuild(j, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := cancelBuild(j); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := followLog(j, buildUrl, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := j.GetProgressiveHtml(buildUrl, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	version := r.Cassette().Jenkins()
+	if version == "" {
+		t.Fatal("the controller sent no X-Jenkins version")
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("testdata", "lts-"+version+".json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// forEachLTS runs the test with a client replaying each LTS cassette. Nothing
+// listens on the address of the client.
+func forEachLTS(t *testing.T, test func(t *testing.T, j *jenkins.Jenkins, r *Recorder)) {
+	files, err := filepath.Glob(filepath.Join("testdata", "lts-*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no LTS cassettes in testdata")
+	}
+	for _, file := range files {
+		r, err := New(file, Replay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Run(r.Cassette().Jenkins(), func(t *testing.T) {
+			j := jenkins.NewJenkins("http://127.0.0.1:9", "bob", "key")
+			// Requests which were not recorded fail at once
+			j.Retry = jenkins.RetryPolicy{}
+			r.Wrap(j)
+			test(t, j, r)
+		})
+	}
+}
+
+func TestLTSReplacePipelineScript(t *testing.T) {
+	forEachLTS(t, func(t *testing.T, j *jenkins.Jenkins, r *Recorder) {
+		before, sent, after, err := replaceScript(j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The controller accepted the config sent while recording
+		for _, interaction := range r.Cassette().Interactions {
+			if interaction.Request.Method == "POST" && strings.HasSuffix(interaction.Request.Url, "/config.xml") && interaction.Request.Body != sent {
+				t.Errorf("sent config\n%s\ndiffers from the recorded one\n%s", sent, interaction.Request.Body)
+			}
+		}
+		if scriptRegexp.ReplaceAllString(sent, "") != scriptRegexp.ReplaceAllString(before, "") {
+			t.Errorf("more than the script changed in\n%s\nwhich was\n%s", sent, before)
+		}
+		// The controller writes the config again in its own way
+		for _, config := range []string{sent, after} {
+			if script, err := jenkins.ExtractPipelineScript(config); err != nil || script != ltsScript {
+				t.Errorf("ExtractPipelineScript() = %q, %v, want %q in\n%s", script, err, ltsScript, config)
+			}
+		}
+		// which escapes other characters than the client
+		again, err := jenkins.ReplacePipelineScript(after, ltsScript)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, errWant := jenkins.NormalizeConfig(scriptRegexp.ReplaceAllString(after, ""))
+		got, errGot := jenkins.NormalizeConfig(scriptRegexp.ReplaceAllString(again, ""))
+		if errWant != nil || errGot != nil || got != want {
+			t.Errorf("replacing the script in the written config changed more than the script:\n%s", again)
+		}
+	})
+}
+
+func TestLTSQueue(t *testing.T) {
+	forEachLTS(t, func(t *testing.T, j *jenkins.Jenkins, r *Recorder) {
+		buildUrl, err := waitForBuild(j, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(buildUrl, j.JobUrl("team/app")+"/") || jenkins.JobNameFromUrl(buildUrl) != "team/app" {
+			t.Errorf("build URL %q is no build of team/app", buildUrl)
+		}
+
+		item, buildUrl, inQueue, err := cancelBuild(j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.Id == 0 || item.Why == "" || item.Task.Name != "app" || item.Cancelled {
+			t.Errorf("GetQueueItem() = %+v, want a waiting build of app", item)
+		}
+		if inQueue || buildUrl != "" {
+			t.Errorf("CheckInQueue() of the cancelled item = %q, %v, want no build", buildUrl, inQueue)
+		}
+	})
+}
+
+func TestLTSLog(t *testing.T) {
+	forEachLTS(t, func(t *testing.T, j *jenkins.Jenkins, r *Recorder) {
+		buildUrl, err := waitForBuild(j, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks, err := followLog(j, buildUrl, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chunks) < 3 {
+			t.Errorf("the log was read in %d polls, want it streamed", len(chunks))
+		}
+		var log strings.Builder
+		for i, chunk := range chunks {
+			// The offsets count the console notes, which are not sent
+			if chunk.next < chunk.start+int64(len(chunk.text)) {
+				t.Errorf("poll %d: next offset %d is before the end of the text at %d", i, chunk.next, chunk.start+int64(len(chunk.text)))
+			}
+			log.WriteString(chunk.text)
+		}
+		text := log.String()
+		if jenkins.StripConsoleNotes(text) != text {
+			t.Errorf("the log has console notes:\n%q", text)
+		}
+		for _, want := range []string{"Started by user bob\n", "building team/app & <friends>\n", "\nFinished: SUCCESS\n"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("the log does not contain %q:\n%s", want, text)
+			}
+		}
+
+		html, _, more, err := j.GetProgressiveHtml(buildUrl, 0)
+		if err != nil || more {
+			t.Fatalf("GetProgressiveHtml() of the finished build = %v, %v", more, err)
+		}
+		converted := jenkins.HTMLToANSI(html, buildUrl, true)
+		if got := jenkins.StripANSI(converted); got != text {
+			t.Errorf("the HTML log is\n%q\nwant the text log\n%q", got, text)
+		}
+		if link := "\x1b]8;;http://127.0.0.1:9/user/bob\x1b\\"; !strings.Contains(converted, link) {
+			t.Errorf("the HTML log has no link to the user:\n%q", converted)
+		}
+	})
+}
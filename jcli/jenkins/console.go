@@ -0,0 +1,419 @@
+package jenkins
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Jenkins serializes ConsoleNote annotations into the log as a hidden
+	// base64 blob between the preamble ESC[8mha: and the postamble ESC[0m
+	consoleNoteRegexp = regexp.MustCompile(`\x1b\[8mha:[^\x1b]*\x1b\[0m`)
+	// escapeRegexp matches CSI sequences, OSC sequences and other escapes
+	escapeRegexp   = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[@-Z\\-_])`)
+	htmlTagRegexp  = regexp.MustCompile(`<(/?)([a-zA-Z]+)([^>]*)>`)
+	htmlAttrRegexp = regexp.MustCompile(`([a-zA-Z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	cssColorRegexp = regexp.MustCompile(`(background-color|color)\s*:\s*#([0-9a-fA-F]{6})`)
+)
+
+// StripConsoleNotes removes the encoded ConsoleNote annotations from a log
+func StripConsoleNotes(log string) string {
+	return consoleNoteRegexp.ReplaceAllString(log, "")
+}
+
+// StripANSI removes all escape sequences from the log
+func StripANSI(log string) string {
+	return escapeRegexp.ReplaceAllString(log, "")
+}
+
+// SanitizeANSI keeps the SGR sequences of a log, which set colors and text
+// attributes, and removes all other escape sequences like cursor movements.
+// Colors are reset at the end of every line and restored on the next one,
+// so an open color never bleeds into the borders drawn around the log.
+// Carriage returns used to redraw progress output keep only the last redraw.
+func SanitizeANSI(log string) string {
+	lines := strings.Split(log, "\n")
+	var state sgrState
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if idx := strings.LastIndex(line, "\r"); idx >= 0 {
+			line = line[idx+1:]
+		}
+		prefix := state.String()
+		line = escapeRegexp.ReplaceAllStringFunc(line, func(seq string) string {
+			params, ok := parseSGR(seq)
+			if !ok {
+				return ""
+			}
+			state.apply(params)
+			return seq
+		})
+		if prefix != "" || strings.Contains(line, "\x1b[") {
+			line = prefix + line + "\x1b[0m"
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseSGR returns the parameters of an SGR sequence. Empty parameters are
+// 0, so ESC[m resets like ESC[0m.
+func parseSGR(seq string) ([]int, bool) {
+	if !strings.HasPrefix(seq, "\x1b[") || !strings.HasSuffix(seq, "m") {
+		return nil, false
+	}
+	var params []int
+	for _, p := range strings.Split(seq[2:len(seq)-1], ";") {
+		n, _ := strconv.Atoi(p)
+		params = append(params, n)
+	}
+	return params, true
+}
+
+// sgrState is the state of the colors and text attributes set by SGR
+// sequences, kept as the parameters which restore it
+type sgrState struct {
+	// fg and bg are the parameters of the colors, like 31 or 38;5;208
+	fg, bg string
+	// attrs are the attributes from bold to strikethrough, indexed by the
+	// parameters 1 to 9 which set them
+	attrs [10]bool
+}
+
+// apply updates the state with the parameters of an SGR sequence
+func (s *sgrState) apply(params []int) {
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == 0:
+			*s = sgrState{}
+		case p >= 1 && p <= 9:
+			s.attrs[p] = true
+		case p == 22:
+			s.attrs[1], s.attrs[2] = false, false
+		case p == 25:
+			s.attrs[5], s.attrs[6] = false, false
+		case p == 23 || p == 24 || (p >= 27 && p <= 29):
+			s.attrs[p-20] = false
+		case p >= 30 && p <= 37, p >= 90 && p <= 97:
+			s.fg = strconv.Itoa(p)
+		case p == 39:
+			s.fg = ""
+		case p >= 40 && p <= 47, p >= 100 && p <= 107:
+			s.bg = strconv.Itoa(p)
+		case p == 49:
+			s.bg = ""
+		case p == 38 || p == 48:
+			n := 0
+			if i+2 < len(params) && params[i+1] == 5 {
+				n = 2
+			} else if i+4 < len(params) && params[i+1] == 2 {
+				n = 4
+			} else {
+				// The color is incomplete, so the rest cannot be parsed
+				return
+			}
+			color := make([]string, n+1)
+			for k := range color {
+				color[k] = strconv.Itoa(params[i+k])
+			}
+			if p == 38 {
+				s.fg = strings.Join(color, ";")
+			} else {
+				s.bg = strings.Join(color, ";")
+			}
+			i += n
+		}
+	}
+}
+
+// String returns the SGR sequence which restores the state, which is empty
+// for the default state
+func (s sgrState) String() string {
+	var params []string
+	for p, set := range s.attrs {
+		if set {
+			params = append(params, strconv.Itoa(p))
+		}
+	}
+	if s.fg != "" {
+		params = append(params, s.fg)
+	}
+	if s.bg != "" {
+		params = append(params, s.bg)
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// HTMLToANSI converts the HTML console log served by progressiveHtml into
+// text with ANSI colors. Relative links are resolved against baseUrl. With
+// hyperlinks set, links are written as OSC 8 hyperlinks, otherwise the
+// target is appended to the underlined link text.
+func HTMLToANSI(console, baseUrl string, hyperlinks bool) string {
+	var out strings.Builder
+	// Every open tag pushes the SGR sequences it adds, closing tags pop them
+	type openTag struct {
+		name string
+		sgr  string
+		href string
+	}
+	var stack []openTag
+	restore := func() {
+		out.WriteString("\x1b[0m")
+		for _, t := range stack {
+			out.WriteString(t.sgr)
+		}
+	}
+	pos := 0
+	for _, m := range htmlTagRegexp.FindAllStringSubmatchIndex(console, -1) {
+		out.WriteString(html.UnescapeString(console[pos:m[0]]))
+		pos = m[1]
+		closing := console[m[2]:m[3]] == "/"
+		name := strings.ToLower(console[m[4]:m[5]])
+		attrs := console[m[6]:m[7]]
+
+		if name == "br" {
+			out.WriteString("\n")
+			continue
+		}
+		if closing {
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].name != name {
+					continue
+				}
+				t := stack[i]
+				stack = append(stack[:i], stack[i+1:]...)
+				if t.sgr != "" {
+					restore()
+				}
+				if t.href != "" {
+					if hyperlinks {
+						out.WriteString("\x1b]8;;\x1b\\")
+					} else {
+						out.WriteString(" <" + t.href + ">")
+					}
+				}
+				break
+			}
+			continue
+		}
+
+		t := openTag{name: name}
+		switch name {
+		case "span", "font", "div":
+			t.sgr = styleToSGR(htmlAttr(attrs, "style"))
+		case "b", "strong":
+			t.sgr = "\x1b[1m"
+		case "i", "em":
+			t.sgr = "\x1b[3m"
+		case "a":
+			t.href = resolveLink(html.UnescapeString(htmlAttr(attrs, "href")), baseUrl)
+			if t.href == "" {
+				break
+			}
+			if hyperlinks {
+				out.WriteString("\x1b]8;;" + t.href + "\x1b\\")
+			} else {
+				t.sgr = "\x1b[4m"
+			}
+		default:
+			continue
+		}
+		out.WriteString(t.sgr)
+		stack = append(stack, t)
+	}
+	out.WriteString(html.UnescapeString(console[pos:]))
+	if len(stack) > 0 {
+		out.WriteString("\x1b[0m")
+	}
+	return out.String()
+}
+
+// htmlAttr returns the value of the named attribute, which Jenkins quotes
+// with single quotes in the links it renders
+func htmlAttr(attrs, name string) string {
+	for _, m := range htmlAttrRegexp.FindAllStringSubmatch(attrs, -1) {
+		if strings.EqualFold(m[1], name) {
+			return m[2] + m[3]
+		}
+	}
+	return ""
+}
+
+// styleToSGR translates the colors and font weight of an inline CSS style,
+// as written by the AnsiColor plugin, into SGR sequences
+func styleToSGR(style string) string {
+	var sgr strings.Builder
+	for _, m := range cssColorRegexp.FindAllStringSubmatch(style, -1) {
+		rgb, _ := strconv.ParseUint(m[2], 16, 32)
+		code := 38
+		if m[1] == "background-color" {
+			code = 48
+		}
+		fmt.Fprintf(&sgr, "\x1b[%d;2;%d;%d;%dm", code, rgb>>16, rgb>>8&0xff, rgb&0xff)
+	}
+	if strings.Contains(style, "font-weight: bold") || strings.Contains(style, "font-weight:bold") {
+		sgr.WriteString("\x1b[1m")
+	}
+	if strings.Contains(style, "text-decoration: underline") {
+		sgr.WriteString("\x1b[4m")
+	}
+	return sgr.String()
+}
+
+// resolveLink makes a link of the console absolute
+func resolveLink(href, baseUrl string) string {
+	if href == "" || strings.HasPrefix(href, "#") {
+		return ""
+	}
+	base, err := url.Parse(baseUrl)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// ansiColors are the xterm colors of the 16 basic ANSI colors
+var ansiColors = [16]string{
+	"#000000", "#cd0000", "#00cd00", "#cdcd00", "#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+	"#7f7f7f", "#ff0000", "#00ff00", "#ffff00", "#5c5cff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+// ansiStyle is the state of the text attributes set by SGR sequences
+type ansiStyle struct {
+	fg, bg                  string
+	bold, italic, underline bool
+}
+
+func (s ansiStyle) css() string {
+	var css []string
+	if s.fg != "" {
+		css = append(css, "color: "+s.fg)
+	}
+	if s.bg != "" {
+		css = append(css, "background-color: "+s.bg)
+	}
+	if s.bold {
+		css = append(css, "font-weight: bold")
+	}
+	if s.italic {
+		css = append(css, "font-style: italic")
+	}
+	if s.underline {
+		css = append(css, "text-decoration: underline")
+	}
+	return strings.Join(css, "; ")
+}
+
+// color256 returns the CSS color of an xterm 256 color index
+func color256(n int) string {
+	switch {
+	case n < 16:
+		return ansiColors[n]
+	case n < 232:
+		n -= 16
+		levels := []int{0, 95, 135, 175, 215, 255}
+		return fmt.Sprintf("#%02x%02x%02x", levels[n/36], levels[n/6%6], levels[n%6])
+	default:
+		gray := 8 + (n-232)*10
+		return fmt.Sprintf("#%02x%02x%02x", gray, gray, gray)
+	}
+}
+
+// apply updates the style with the parameters of an SGR sequence
+func (s *ansiStyle) apply(params []int) {
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == 0:
+			*s = ansiStyle{}
+		case p == 1:
+			s.bold = true
+		case p == 3:
+			s.italic = true
+		case p == 4:
+			s.underline = true
+		case p == 22:
+			s.bold = false
+		case p == 23:
+			s.italic = false
+		case p == 24:
+			s.underline = false
+		case p >= 30 && p <= 37:
+			s.fg = ansiColors[p-30]
+		case p >= 90 && p <= 97:
+			s.fg = ansiColors[p-90+8]
+		case p == 39:
+			s.fg = ""
+		case p >= 40 && p <= 47:
+			s.bg = ansiColors[p-40]
+		case p >= 100 && p <= 107:
+			s.bg = ansiColors[p-100+8]
+		case p == 49:
+			s.bg = ""
+		case (p == 38 || p == 48) && i+2 < len(params) && params[i+1] == 5:
+			color := color256(params[i+2] & 0xff)
+			if p == 38 {
+				s.fg = color
+			} else {
+				s.bg = color
+			}
+			i += 2
+		case (p == 38 || p == 48) && i+4 < len(params) && params[i+1] == 2:
+			color := fmt.Sprintf("#%02x%02x%02x", params[i+2]&0xff, params[i+3]&0xff, params[i+4]&0xff)
+			if p == 38 {
+				s.fg = color
+			} else {
+				s.bg = color
+			}
+			i += 4
+		}
+	}
+}
+
+// ANSIToHTML converts a log with ANSI colors into a standalone HTML page
+func ANSIToHTML(log, title string) string {
+	var out strings.Builder
+	out.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	out.WriteString("<title>" + html.EscapeString(title) + "</title>\n")
+	out.WriteString("<style>body { background: #1e1e1e; color: #e5e5e5; } pre { font-family: monospace; white-space: pre-wrap; }</style>\n")
+	out.WriteString("</head>\n<body>\n<pre>")
+
+	var style ansiStyle
+	open := false
+	pos := 0
+	for _, m := range escapeRegexp.FindAllStringIndex(log, -1) {
+		out.WriteString(html.EscapeString(log[pos:m[0]]))
+		pos = m[1]
+		params, ok := parseSGR(log[m[0]:m[1]])
+		if !ok {
+			continue
+		}
+		style.apply(params)
+		if open {
+			out.WriteString("</span>")
+			open = false
+		}
+		if css := style.css(); css != "" {
+			out.WriteString(`<span style="` + css + `">`)
+			open = true
+		}
+	}
+	out.WriteString(html.EscapeString(log[pos:]))
+	if open {
+		out.WriteString("</span>")
+	}
+	out.WriteString("</pre>\n</body>\n</html>\n")
+	return out.String()
+}
@@ -0,0 +1,105 @@
+package jenkins
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripConsoleNotes(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"\x1b[8mha:////4AAAAWB+LCAAAAAAAAP9b\x1b[0m[Pipeline] echo\n", "[Pipeline] echo\n"},
+		{"a\x1b[8mha:AAA=\x1b[0mb\x1b[8mha:BBB=\x1b[0mc", "abc"},
+		// Colors of the log stay
+		{"\x1b[31mred\x1b[0m\n", "\x1b[31mred\x1b[0m\n"},
+		{"Finished: SUCCESS\n", "Finished: SUCCESS\n"},
+	} {
+		if got := StripConsoleNotes(test.in); got != test.want {
+			t.Errorf("StripConsoleNotes(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestSanitizeANSI(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"plain\nlog", "plain\nlog"},
+		// Colors are reset at the end of the line and restored on the next
+		{"\x1b[31mred\nstill red\x1b[0m\nplain", "\x1b[31mred\x1b[0m\n\x1b[31mstill red\x1b[0m\x1b[0m\nplain"},
+		// Partial resets end single attributes
+		{"\x1b[1m\x1b[31ma\x1b[32m\nb\x1b[39m\nc\x1b[22m\nd", "\x1b[1m\x1b[31ma\x1b[32m\x1b[0m\n\x1b[1;32mb\x1b[39m\x1b[0m\n\x1b[1mc\x1b[22m\x1b[0m\nd"},
+		{"\x1b[44;4mx\x1b[49m\ny\x1b[24m\nz", "\x1b[44;4mx\x1b[49m\x1b[0m\n\x1b[4my\x1b[24m\x1b[0m\nz"},
+		// Repeated colors do not grow the restored state
+		{"\x1b[31ma\n\x1b[31mb\n\x1b[31mc", "\x1b[31ma\x1b[0m\n\x1b[31m\x1b[31mb\x1b[0m\n\x1b[31m\x1b[31mc\x1b[0m"},
+		{"\x1b[38;5;208;48;2;1;2;3mx\ny\x1b[m\nz", "\x1b[38;5;208;48;2;1;2;3mx\x1b[0m\n\x1b[38;5;208;48;2;1;2;3my\x1b[m\x1b[0m\nz"},
+		// Other escapes are removed
+		{"\x1b[2K\x1b[1Gdone", "done"},
+		{"\x1b]8;;http://ci\x1b\\link\x1b]8;;\x1b\\", "link"},
+		// Progress output keeps the last redraw
+		{"10%\r50%\r100%\r\nnext", "100%\nnext"},
+	} {
+		if got := SanitizeANSI(test.in); got != test.want {
+			t.Errorf("SanitizeANSI(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestSanitizeANSILongLog(t *testing.T) {
+	line := "\x1b[31mred\x1b[39m \x1b[1mbold\x1b[22m\x1b[34m"
+	got := SanitizeANSI(strings.Repeat(line+"\n", 10000))
+	lines := strings.Split(got, "\n")
+	if want := "\x1b[34m" + line + "\x1b[0m"; lines[9999] != want {
+		t.Errorf("last line = %q, want %q", lines[9999], want)
+	}
+}
+
+func TestHTMLToANSI(t *testing.T) {
+	base := "http://ci.local/job/app/2/"
+	for _, test := range []struct {
+		in         string
+		hyperlinks bool
+		want       string
+	}{
+		{"<b>bold</b> text", false, "\x1b[1mbold\x1b[0m text"},
+		{`<span style="color: #CD0000;">red</span><br>`, false, "\x1b[38;2;205;0;0mred\x1b[0m\n"},
+		// Closing a tag restores the ones still open
+		{`<b>x<span style="background-color: #00CD00">y</span>z</b>`, false, "\x1b[1mx\x1b[48;2;0;205;0my\x1b[0m\x1b[1mz\x1b[0m"},
+		{"a &lt;b&gt; &amp; c", false, "a <b> & c"},
+		{`<a href="/job/app/1/">#1</a>`, false, "\x1b[4m#1\x1b[0m <http://ci.local/job/app/1/>"},
+		{`<a href="../1/console">#1</a>`, true, "\x1b]8;;http://ci.local/job/app/1/console\x1b\\#1\x1b]8;;\x1b\\"},
+		{`<a href="#top">top</a>`, true, "top"},
+		{`Started by user <a href='/user/alice' class='model-link'>Alice</a>`, true, "Started by user \x1b]8;;http://ci.local/user/alice\x1b\\Alice\x1b]8;;\x1b\\"},
+		// Unknown tags are dropped
+		{`<img src="x.png"><i>it</i>`, false, "\x1b[3mit\x1b[0m"},
+	} {
+		if got := HTMLToANSI(test.in, base, test.hyperlinks); got != test.want {
+			t.Errorf("HTMLToANSI(%q, %v) = %q, want %q", test.in, test.hyperlinks, got, test.want)
+		}
+	}
+}
+
+func TestANSIToHTML(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"a\x1b[31mred\x1b[0m <b>", `a<span style="color: #cd0000">red</span> &lt;b&gt;`},
+		{"\x1b[1;38;5;208mx\x1b[22my", `<span style="color: #ff8700; font-weight: bold">x</span><span style="color: #ff8700">y</span>`},
+		{"\x1b[44;3mx\x1b[49my\x1b[m", `<span style="background-color: #0000ee; font-style: italic">x</span><span style="font-style: italic">y</span>`},
+		{"\x1b[2Kplain", "plain"},
+		{"\x1b[4;38;2;1;2;3mopen", `<span style="color: #010203; text-decoration: underline">open</span>`},
+	} {
+		page := ANSIToHTML(test.in, "app #1")
+		start, end := strings.Index(page, "<pre>"), strings.Index(page, "</pre>")
+		if start < 0 || end < 0 {
+			t.Fatalf("ANSIToHTML(%q) has no <pre>:\n%s", test.in, page)
+		}
+		if got := page[start+len("<pre>") : end]; got != test.want {
+			t.Errorf("ANSIToHTML(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+	if page := ANSIToHTML("", "a & b"); !strings.Contains(page, "<title>a &amp; b</title>") {
+		t.Errorf("title is not escaped:\n%s", page)
+	}
+}
@@ -0,0 +1,216 @@
+package jenkins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// declarativeRegexp matches the pipeline block of a declarative pipeline
+var declarativeRegexp = regexp.MustCompile(`(?m)^\s*pipeline\s*\{`)
+
+// ErrLinterUnavailable is returned if the Pipeline Model Definition plugin,
+// which validates Jenkinsfiles, is not installed
+var ErrLinterUnavailable = errors.New("the pipeline linter is not available on the server")
+
+// IsDeclarative reports whether the script is a declarative pipeline.
+// Scripted pipelines cannot be validated by the server.
+func IsDeclarative(script string) bool {
+	return declarativeRegexp.MatchString(script)
+}
+
+// converterResponse is the envelope of the pipeline-model-converter endpoints
+type converterResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Result string `json:"result"`
+		// JSON is the result of toJson
+		JSON json.RawMessage `json:"json"`
+		// Jenkinsfile is the result of toJenkinsfile
+		Jenkinsfile string `json:"jenkinsfile"`
+		Errors      []struct {
+			// Error is a message or a list of messages
+			Error json.RawMessage `json:"error"`
+		} `json:"errors"`
+	} `json:"data"`
+}
+
+// messages returns the error messages of a failed conversion
+func (r converterResponse) messages() []string {
+	var messages []string
+	for _, e := range r.Data.Errors {
+		var list []string
+		if err := json.Unmarshal(e.Error, &list); err == nil {
+			messages = append(messages, list...)
+			continue
+		}
+		var msg string
+		if err := json.Unmarshal(e.Error, &msg); err == nil {
+			messages = append(messages, msg)
+			continue
+		}
+		messages = append(messages, string(e.Error))
+	}
+	return messages
+}
+
+// ConversionError is returned if the server cannot convert a pipeline, with
+// the reasons it gives
+type ConversionError struct {
+	Messages []string
+}
+
+func (e *ConversionError) Error() string {
+	if len(e.Messages) == 0 {
+		return "conversion failed"
+	}
+	return "conversion failed: " + strings.Join(e.Messages, "; ")
+}
+
+// conversionError returns the error of a conversion which did not succeed
+func (r converterResponse) conversionError() error {
+	if r.Data.Result == "success" {
+		return nil
+	}
+	return &ConversionError{Messages: r.messages()}
+}
+
+// postConverter posts the form to an endpoint of the pipeline-model-converter
+// and decodes the response
+func (j *Jenkins) postConverter(endpoint string, form url.Values, v any) error {
+	apiUrl := j.JobUrl("") + "/pipeline-model-converter/" + endpoint
+	req, err := j.newRequest("POST", apiUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := j.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrLinterUnavailable
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("POST %s: %s", apiUrl, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// ValidatePipeline lints a declarative pipeline on the server. It returns
+// the errors found, which are empty if the pipeline is valid.
+func (j *Jenkins) ValidatePipeline(script string) ([]string, error) {
+	var resp converterResponse
+	if err := j.postConverter("validateJenkinsfile", url.Values{"jenkinsfile": {script}}, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Data.Result == "success" {
+		return nil, nil
+	}
+	messages := resp.messages()
+	if len(messages) == 0 {
+		messages = []string{"validation failed: " + resp.Data.Result}
+	}
+	return messages, nil
+}
+
+// PipelineToJSON converts a declarative pipeline to its JSON representation
+// on the server
+func (j *Jenkins) PipelineToJSON(script string) (json.RawMessage, error) {
+	var resp converterResponse
+	if err := j.postConverter("toJson", url.Values{"jenkinsfile": {script}}, &resp); err != nil {
+		return nil, err
+	}
+	if err := resp.conversionError(); err != nil {
+		return nil, err
+	}
+	return resp.Data.JSON, nil
+}
+
+// PipelineFromJSON converts the JSON representation of a declarative
+// pipeline to a Jenkinsfile on the server
+func (j *Jenkins) PipelineFromJSON(data []byte) (string, error) {
+	var resp converterResponse
+	if err := j.postConverter("toJenkinsfile", url.Values{"json": {string(data)}}, &resp); err != nil {
+		return "", err
+	}
+	if err := resp.conversionError(); err != nil {
+		return "", err
+	}
+	return resp.Data.Jenkinsfile, nil
+}
+
+// PipelineAgent is where a declarative pipeline or stage runs
+type PipelineAgent struct {
+	Type string `json:"type"`
+	// Argument is the label of label agents
+	Argument *pipelineValue `json:"argument"`
+	// Arguments are the options of other agents, like the image of docker
+	Arguments []struct {
+		Key   string        `json:"key"`
+		Value pipelineValue `json:"value"`
+	} `json:"arguments"`
+}
+
+// pipelineValue is a value in the JSON representation of a pipeline
+type pipelineValue struct {
+	IsLiteral bool `json:"isLiteral"`
+	Value     any  `json:"value"`
+}
+
+func (v pipelineValue) String() string {
+	if v.IsLiteral {
+		return fmt.Sprint(v.Value)
+	}
+	// Expressions like ${params.LABEL}
+	return fmt.Sprintf("${%v}", v.Value)
+}
+
+func (a *PipelineAgent) String() string {
+	if a == nil {
+		return ""
+	}
+	s := a.Type
+	if a.Argument != nil {
+		s += " " + a.Argument.String()
+	}
+	for _, arg := range a.Arguments {
+		s += " " + arg.Key + "=" + arg.Value.String()
+	}
+	return s
+}
+
+// PipelineStage is a stage of a declarative pipeline with the stages which
+// run in it in parallel or one after another
+type PipelineStage struct {
+	Name     string          `json:"name"`
+	Agent    *PipelineAgent  `json:"agent"`
+	Parallel []PipelineStage `json:"parallel"`
+	Stages   []PipelineStage `json:"stages"`
+}
+
+// PipelineSummary is the agent and stages a declarative pipeline declares
+type PipelineSummary struct {
+	Agent  *PipelineAgent  `json:"agent"`
+	Stages []PipelineStage `json:"stages"`
+}
+
+// SummarizePipeline reads the agent and stages from the JSON representation
+// of a declarative pipeline
+func SummarizePipeline(data []byte) (*PipelineSummary, error) {
+	var model struct {
+		Pipeline *PipelineSummary `json:"pipeline"`
+	}
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, err
+	}
+	if model.Pipeline == nil {
+		return nil, errors.New("no pipeline in the JSON")
+	}
+	return model.Pipeline, nil
+}
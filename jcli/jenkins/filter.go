@@ -0,0 +1,118 @@
+package jenkins
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type FilterAction int
+
+const (
+	// FilterExclude removes the lines matching the pattern
+	FilterExclude FilterAction = iota
+	// FilterInclude keeps only lines matching the pattern or another include rule
+	FilterInclude
+	// FilterRewrite replaces the matches of the pattern with the replacement
+	FilterRewrite
+)
+
+type FilterRule struct {
+	Action      FilterAction
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// FilterPresets are the built-in rule sets which can be enabled by name
+var FilterPresets = map[string][]FilterRule{
+	// Step markers like [Pipeline] stage or [Pipeline] { printed by Pipeline
+	"pipeline": {
+		{Action: FilterExclude, Pattern: regexp.MustCompile(`^\[Pipeline\]`)},
+	},
+	// Timestamps added by the Timestamper plugin or the build tools
+	"timestamps": {
+		{Action: FilterRewrite, Pattern: regexp.MustCompile(`^\[?(?:\d{4}-\d{2}-\d{2}[T ])?\d{2}:\d{2}:\d{2}(?:[.,]\d+)?Z?\]?\s+`)},
+	},
+	// The commands echoed by sh steps, which run the shell with -x
+	"shell-echo": {
+		{Action: FilterExclude, Pattern: regexp.MustCompile(`^\++ `)},
+	},
+}
+
+// LogFilter removes and rewrites lines of a console log
+type LogFilter struct {
+	rules []FilterRule
+}
+
+// PresetNames returns the names of the built-in presets
+func PresetNames() []string {
+	names := make([]string, 0, len(FilterPresets))
+	for name := range FilterPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewLogFilter creates a filter from the named presets followed by the
+// given rules
+func NewLogFilter(presets []string, rules []FilterRule) (*LogFilter, error) {
+	f := &LogFilter{}
+	for _, name := range presets {
+		preset, ok := FilterPresets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown log filter preset %q, available are %s", name, strings.Join(PresetNames(), ", "))
+		}
+		f.rules = append(f.rules, preset...)
+	}
+	f.rules = append(f.rules, rules...)
+	return f, nil
+}
+
+// Apply filters the log line by line. Lines matching an exclude rule are
+// dropped. If there are include rules, only lines matching one of them are
+// kept. The rewrite rules are applied to the remaining lines in order.
+// All patterns are matched against the line without colors, so lines which
+// are rewritten lose their colors.
+func (f *LogFilter) Apply(log string) string {
+	if f == nil || len(f.rules) == 0 {
+		return log
+	}
+	hasInclude := false
+	for _, rule := range f.rules {
+		if rule.Action == FilterInclude {
+			hasInclude = true
+		}
+	}
+
+	lines := strings.SplitAfter(log, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		plain := StripANSI(line)
+		excluded, included := false, !hasInclude
+		for _, rule := range f.rules {
+			switch rule.Action {
+			case FilterExclude:
+				excluded = excluded || rule.Pattern.MatchString(plain)
+			case FilterInclude:
+				included = included || rule.Pattern.MatchString(plain)
+			}
+		}
+		if excluded || !included {
+			continue
+		}
+		rewritten := plain
+		for _, rule := range f.rules {
+			if rule.Action == FilterRewrite {
+				rewritten = rule.Pattern.ReplaceAllString(rewritten, rule.Replacement)
+			}
+		}
+		if rewritten != plain {
+			// Rewritten lines lose their colors
+			line = rewritten
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "")
+}
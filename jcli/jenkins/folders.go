@@ -0,0 +1,91 @@
+package jenkins
+
+import (
+	"net/url"
+	"strings"
+)
+
+// folderClasses are the item types which contain other jobs
+var folderClasses = []string{
+	"com.cloudbees.hudson.plugins.folder.Folder",
+	"jenkins.branch.OrganizationFolder",
+	"org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject",
+}
+
+// generatingClasses are the folders whose jobs are generated from SCM
+var generatingClasses = []string{
+	"jenkins.branch.OrganizationFolder",
+	"org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject",
+}
+
+type Item struct {
+	Class    string `json:"_class"`
+	Name     string `json:"name"`
+	FullName string `json:"fullName"`
+	Url      string `json:"url"`
+}
+
+// IsFolder reports whether the item contains other jobs
+func (i Item) IsFolder() bool {
+	for _, class := range folderClasses {
+		if i.Class == class {
+			return true
+		}
+	}
+	return false
+}
+
+// GeneratesJobs reports whether the jobs in the folder are generated, like
+// the branch jobs of multibranch projects
+func (i Item) GeneratesJobs() bool {
+	for _, class := range generatingClasses {
+		if i.Class == class {
+			return true
+		}
+	}
+	return false
+}
+
+// ListItems returns the jobs and folders directly inside a folder. An empty
+// folder lists the items at the top level of the server.
+func (j *Jenkins) ListItems(folder string) ([]Item, error) {
+	var items struct {
+		Jobs []Item `json:"jobs"`
+	}
+	tree := url.QueryEscape("jobs[_class,name,fullName,url]")
+	if err := j.getJSON(j.JobUrl(folder)+"/api/json?tree="+tree, &items); err != nil {
+		return nil, err
+	}
+	return items.Jobs, nil
+}
+
+// ListJobs returns all jobs inside a folder and its subfolders
+func (j *Jenkins) ListJobs(folder string) ([]Item, error) {
+	items, err := j.ListItems(folder)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []Item
+	for _, item := range items {
+		if !item.IsFolder() {
+			jobs = append(jobs, item)
+			continue
+		}
+		children, err := j.ListJobs(item.FullName)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, children...)
+	}
+	return jobs, nil
+}
+
+// ParentFolder returns the folder containing a job, or an empty string for
+// jobs on the top level
+func ParentFolder(jobName string) string {
+	jobName = strings.Trim(jobName, "/")
+	if idx := strings.LastIndex(jobName, "/"); idx >= 0 {
+		return jobName[:idx]
+	}
+	return ""
+}
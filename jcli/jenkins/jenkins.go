@@ -1,137 +1,223 @@
 package jenkins
 
 import (
-	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
-	"os"
+	"strings"
+	"sync"
 	"time"
 )
 
-type QueueInfo struct {
-	Reason   string        `json:"why"`
-	Location BuildLocation `json:"executable"`
-}
-
-type BuildLocation struct {
-	Url string `json:"url"`
-}
-
 type Jenkins struct {
 	Address string
 	User    string
 	APIKey  string
+	// Client sends the requests. It keeps the session cookie, which the
+	// CSRF crumb is bound to.
+	Client *http.Client
+	// Retry sets how reading requests are retried on transient failures
+	Retry RetryPolicy
+	// OnRetry is called before waiting to retry a request, with the failed
+	// response or error
+	OnRetry func(req *http.Request, resp *http.Response, err error, wait time.Duration)
+
+	crumbMu      sync.Mutex
+	crumbFetched bool
+	crumbField   string
+	crumb        string
 }
 
 func NewJenkins(address, user, apiKey string) *Jenkins {
+	jar, _ := cookiejar.New(nil)
 	// Load the API Key from the keyring
 	return &Jenkins{
 		Address: address,
 		User:    user,
 		APIKey:  apiKey,
+		Client:  &http.Client{Jar: jar},
+		Retry:   DefaultRetryPolicy,
 	}
 }
 
-func (j *Jenkins) UpdateJobConfig(jobName, updatedConfig string) error {
-	// Make a get request to url at Address to check if Jenkins is alive
-	jobUrl := j.Address + "/job/" + jobName + "/config.xml"
-	req, err := http.NewRequest("POST", jobUrl, bytes.NewBuffer([]byte(updatedConfig)))
+// JobUrl returns the URL of a job. Folder paths such as "team/app" are
+// expanded to Jenkins' nested "/job/team/job/app" form. An empty name
+// returns the URL of the server.
+func (j *Jenkins) JobUrl(jobName string) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSuffix(j.Address, "/"))
+	jobName = strings.Trim(jobName, "/")
+	if jobName == "" {
+		return b.String()
+	}
+	for _, part := range strings.Split(jobName, "/") {
+		b.WriteString("/job/")
+		b.WriteString(url.PathEscape(part))
+	}
+	return b.String()
+}
+
+// newRequest creates a request authenticated with the user's API key
+func (j *Jenkins) newRequest(method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
 	if err != nil {
-		log.Println("Error:", err)
+		return nil, err
 	}
 	req.SetBasicAuth(j.User, j.APIKey)
-	// Set the content type to xml
-	req.Header.Set("Content-Type", "text/xml")
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	return req, nil
+}
+
+// do sends the request to the Jenkins server. Requests which only read are
+// retried on transient failures, requests which change anything carry the
+// CSRF crumb instead.
+func (j *Jenkins) do(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return j.doWithRetry(req)
+	}
+	if err := j.addCrumb(req); err != nil {
+		return nil, err
+	}
+	return j.client().Do(req)
+}
+
+func (j *Jenkins) client() *http.Client {
+	if j.Client == nil {
+		return http.DefaultClient
+	}
+	return j.Client
+}
+
+// addCrumb sets the CSRF crumb header, which servers with CSRF protection
+// require unless the request is authenticated with an API token. The crumb
+// is fetched once from the crumb issuer.
+func (j *Jenkins) addCrumb(req *http.Request) error {
+	j.crumbMu.Lock()
+	defer j.crumbMu.Unlock()
+	if !j.crumbFetched {
+		crumbReq, err := j.newRequest("GET", j.JobUrl("")+"/crumbIssuer/api/json", nil)
+		if err != nil {
+			return err
+		}
+		resp, err := j.client().Do(crumbReq)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		// Servers without CSRF protection have no crumb issuer
+		if resp.StatusCode == http.StatusOK {
+			var crumb struct {
+				Field string `json:"crumbRequestField"`
+				Crumb string `json:"crumb"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&crumb); err != nil {
+				return err
+			}
+			j.crumbField, j.crumb = crumb.Field, crumb.Crumb
+		}
+		j.crumbFetched = true
+	}
+	if j.crumbField != "" {
+		req.Header.Set(j.crumbField, j.crumb)
+	}
+	return nil
+}
+
+// getJSON fetches apiUrl and decodes the JSON response into v
+func (j *Jenkins) getJSON(apiUrl string, v any) error {
+	req, err := j.newRequest("GET", apiUrl, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := j.do(req)
 	if err != nil {
-		log.Println("Error:", err)
-		log.Println("Error: Could not connect to Jenkins server. Please check the address and try again.")
 		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", apiUrl, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
 
+// post sends an empty POST request to apiUrl and fails on error responses
+func (j *Jenkins) post(apiUrl string) error {
+	req, err := j.newRequest("POST", apiUrl, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := j.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("POST %s: %s", apiUrl, resp.Status)
+	}
 	return nil
 }
 
-func (j *Jenkins) CheckJobsExist(jobName string) bool {
-	// Make a get request to url at Address to check if Jenkins is alive
-	jobUrl := j.Address + "/job/" + jobName + "/config.xml"
-	req, err := http.NewRequest("GET", jobUrl, nil)
+func (j *Jenkins) UpdateJobConfig(jobName, updatedConfig string) error {
+	jobUrl := j.JobUrl(jobName) + "/config.xml"
+	req, err := j.newRequest("POST", jobUrl, strings.NewReader(updatedConfig))
 	if err != nil {
-		log.Println("Error:", err)
+		return err
 	}
-	req.SetBasicAuth(j.User, j.APIKey)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// Set the content type to xml
+	req.Header.Set("Content-Type", "text/xml")
+	resp, err := j.do(req)
 	if err != nil {
 		log.Println("Error:", err)
 		log.Println("Error: Could not connect to Jenkins server. Please check the address and try again.")
+		return err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode == 404 {
-		return false
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("POST %s: %s", jobUrl, resp.Status)
 	}
-	return true
+	return nil
 }
 
-// CreateEmptyJob creates a new job with the given name
-func (j *Jenkins) CreateEmptyJob(jobName string) error {
-	// Read the standard config.xml file
-	data, err := os.ReadFile("config.xml")
+func (j *Jenkins) CheckJobsExist(jobName string) bool {
+	jobUrl := j.JobUrl(jobName) + "/config.xml"
+	req, err := j.newRequest("GET", jobUrl, nil)
 	if err != nil {
 		log.Println("Error:", err)
+		return false
 	}
-
-	// Setup the request
-	jobUrl := j.Address + "/createItem"
-	params := url.Values{}
-	params.Add("name", jobName)
-	params.Add("mode", "create")
-	params.Add("Content-Type", "text/xml")
-
-	req, _ := http.NewRequest("POST", jobUrl, bytes.NewBuffer(data))
-	// Add the parameters to the request
-	req.URL.RawQuery = params.Encode()
-	for key, value := range params {
-		req.Header.Add(key, value[0])
-	}
-	req.SetBasicAuth(j.User, j.APIKey)
-
-	// Perform the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := j.do(req)
 	if err != nil {
 		log.Println("Error:", err)
 		log.Println("Error: Could not connect to Jenkins server. Please check the address and try again.")
-		return err
+		return false
 	}
 	defer resp.Body.Close()
-
-	return nil
+	if resp.StatusCode == 404 {
+		return false
+	}
+	return true
 }
 
 func (j *Jenkins) GetJobConfig(jobName string) (string, error) {
-	// Make a get request to url at Address to check if Jenkins is alive
 	log.Println("Getting job config for", jobName)
-	jobUrl := j.Address + "/job/" + jobName + "/config.xml"
-	log.Println("jobURL", jobUrl)
-	req, err := http.NewRequest("GET", jobUrl, nil)
+	jobUrl := j.JobUrl(jobName) + "/config.xml"
+	req, err := j.newRequest("GET", jobUrl, nil)
 	if err != nil {
-		log.Println("Error:", err)
+		return "", err
 	}
-	req.SetBasicAuth(j.User, j.APIKey)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := j.do(req)
 	if err != nil {
 		log.Println("Error:", err)
 		log.Println("Error: Could not connect to Jenkins server. Please check the address and try again.")
 		return "", err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", jobUrl, resp.Status)
+	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Println("Error:", err)
@@ -140,58 +226,63 @@ func (j *Jenkins) GetJobConfig(jobName string) (string, error) {
 	return string(body), nil
 }
 
-// checkInQueue checks if the build is still in the queue
-func (j *Jenkins) CheckInQueue(queueLocation string) (string, bool) {
-	queueUrl := queueLocation + "api/json"
-	req, _ := http.NewRequest("GET", queueUrl, nil)
-	req.SetBasicAuth(j.User, j.APIKey)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// CheckInQueue checks if the build is still in the queue and returns the
+// URL of the build once it started. Cancelled items are no longer in the
+// queue and have no build. Errors mean the item cannot be found anymore or
+// the server stayed unreachable.
+func (j *Jenkins) CheckInQueue(queueLocation string) (string, bool, error) {
+	item, err := j.GetQueueItem(queueLocation)
 	if err != nil {
-		log.Println("Error:", err)
-		log.Println("Error: Could not check build queue for queueLocation", queueLocation)
+		return "", false, err
 	}
-	defer resp.Body.Close()
-	// Get json output from the response
-	var queueInfo QueueInfo
-	json.NewDecoder(resp.Body).Decode(&queueInfo)
-	// If the queueInfo.Reason is not empty, the build is still in the queue
-	if queueInfo.Reason != "" {
-		// log.Println("Info: Build is in queue. Reason:", queueInfo.Reason)
-		return "", true
+	if item.Cancelled {
+		return "", false, nil
 	}
-
-	return queueInfo.Location.Url, false
+	// Items which left the queue have a build, but it is set with a delay
+	if item.Executable == nil {
+		return "", true, nil
+	}
+	return item.Executable.Url, false, nil
 }
 
-func (j *Jenkins) TriggerBuild(jobName string) string {
-	// Trigger the build
-	jobUrl := j.Address + "/job/" + jobName + "/build?delay=0sec"
-	req, _ := http.NewRequest("POST", jobUrl, nil)
-	req.SetBasicAuth(j.User, j.APIKey)
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// QueueBuild schedules a build of the job without waiting for it to start
+// and returns the URL of the queue item
+func (j *Jenkins) QueueBuild(jobName string) (string, error) {
+	jobUrl := j.JobUrl(jobName) + "/build?delay=0sec"
+	req, err := j.newRequest("POST", jobUrl, nil)
 	if err != nil {
-		log.Println("Error:", err)
-		log.Println("Error: Could not trigger build for job", jobName)
+		return "", err
+	}
+	resp, err := j.do(req)
+	if err != nil {
+		return "", err
 	}
 	defer resp.Body.Close()
-	headers := resp.Header
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("POST %s: %s", jobUrl, resp.Status)
+	}
 	// Read queue location from headers
-	queueLocation := headers.Get("Location")
-	// Check if the build is in the queue
+	return resp.Header.Get("Location"), nil
+}
+
+// TriggerBuild builds the job and waits until the build left the queue
+func (j *Jenkins) TriggerBuild(jobName string) (string, error) {
+	queueLocation, err := j.QueueBuild(jobName)
+	if err != nil {
+		return "", err
+	}
 	// Loop until the build is no longer in the queue
-	var buildUrl string
-	var inQueue bool = true
 	for {
-		buildUrl, inQueue = j.CheckInQueue(queueLocation)
-		// Check not in queue and we have valid build url
-		if !inQueue && buildUrl != "" {
-			break
+		buildUrl, inQueue, err := j.CheckInQueue(queueLocation)
+		if err != nil {
+			return "", fmt.Errorf("could not check the build queue at %s: %w", queueLocation, err)
+		}
+		if !inQueue {
+			if buildUrl == "" {
+				return "", fmt.Errorf("the build of %s was cancelled in the queue", jobName)
+			}
+			return buildUrl, nil
 		}
 		time.Sleep(1 * time.Second)
 	}
-	// fmt.Printf("\n🚀 Build started successfully!\n🔗 Build url is: %sconsole\n🗎  Will now serve console output in real time\n\n", buildUrl)
-	// finalMsg := fmt.Sprintf("🚀 Build started successfully!\n🔗 Build url is: %sconsole\n🗎  Will now serve console output in real time\n\n", buildUrl)
-	return buildUrl
 }
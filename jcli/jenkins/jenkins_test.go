@@ -0,0 +1,251 @@
+package jenkins_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"jcli/jenkins"
+	"jcli/jenkins/jenkinstest"
+)
+
+// newServer starts a fake server with the pipeline job app
+func newServer(t *testing.T) *jenkinstest.Server {
+	t.Helper()
+	s := jenkinstest.NewServer()
+	t.Cleanup(s.Close)
+	s.AddJob("team/app", jenkins.PipelineJobConfig)
+	return s
+}
+
+// countRequests returns how many requests the server received with the
+// method for paths starting with the prefix
+func countRequests(s *jenkinstest.Server, method, prefix string) int {
+	n := 0
+	for _, r := range s.Requests() {
+		if r.Method == method && strings.HasPrefix(r.Path, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCheckInQueue(t *testing.T) {
+	s := newServer(t)
+	s.QueuePolls = 2
+	j := s.Client()
+	queueUrl, err := j.QueueBuild("team/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for poll := 1; poll <= s.QueuePolls; poll++ {
+		buildUrl, inQueue, err := j.CheckInQueue(queueUrl)
+		if err != nil || !inQueue || buildUrl != "" {
+			t.Fatalf("poll %d: CheckInQueue() = %q, %v, %v, want the item in the queue", poll, buildUrl, inQueue, err)
+		}
+	}
+	buildUrl, inQueue, err := j.CheckInQueue(queueUrl)
+	if want := s.URL + "/job/team/job/app/1/"; err != nil || inQueue || buildUrl != want {
+		t.Errorf("CheckInQueue() = %q, %v, %v, want %q", buildUrl, inQueue, err, want)
+	}
+	item, err := j.GetQueueItem(queueUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Executable == nil || item.Executable.Number != 1 || item.Task.Name != "app" {
+		t.Errorf("GetQueueItem() = %+v, want build 1 of app", item)
+	}
+}
+
+func TestCheckInQueueCancelled(t *testing.T) {
+	s := newServer(t)
+	s.QueuePolls = 5
+	j := s.Client()
+	queueUrl, err := j.QueueBuild("team/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, err := j.GetQueueItem(queueUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.CancelQueueItem(item.Id); err != nil {
+		t.Fatal(err)
+	}
+	buildUrl, inQueue, err := j.CheckInQueue(queueUrl)
+	if err != nil || inQueue || buildUrl != "" {
+		t.Errorf("CheckInQueue() = %q, %v, %v, want a cancelled item", buildUrl, inQueue, err)
+	}
+}
+
+func TestCheckInQueueMissingItem(t *testing.T) {
+	s := newServer(t)
+	if _, _, err := s.Client().CheckInQueue(s.URL + "/queue/item/42/"); err == nil {
+		t.Error("CheckInQueue of a missing item succeeded")
+	}
+}
+
+func TestTriggerBuild(t *testing.T) {
+	s := newServer(t)
+	buildUrl, err := s.Client().TriggerBuild("team/app")
+	if want := s.URL + "/job/team/job/app/1/"; err != nil || buildUrl != want {
+		t.Errorf("TriggerBuild() = %q, %v, want %q", buildUrl, err, want)
+	}
+	// Queue errors end the wait instead of polling forever
+	s.Fail(jenkinstest.Failure{Path: "/queue/item/", Status: http.StatusNotFound})
+	if _, err := s.Client().TriggerBuild("team/app"); err == nil {
+		t.Error("TriggerBuild succeeded without a queue item")
+	}
+}
+
+func TestGetProgressiveText(t *testing.T) {
+	s := newServer(t)
+	log := "Started by user alice\n[Pipeline] echo\n<b>bold</b>\nFinished: SUCCESS\n"
+	s.SetBuildLog("team/app", log, "SUCCESS")
+	j := s.Client()
+	buildUrl, err := j.TriggerBuild("team/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text strings.Builder
+	var start int64
+	for polls := 1; ; polls++ {
+		if polls > 10 {
+			t.Fatal("the log has more data after 10 polls")
+		}
+		chunk, next, more, err := j.GetProgressiveText(buildUrl, start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The server reveals one line per request
+		if more && strings.Count(chunk, "\n") != 1 {
+			t.Errorf("poll %d returned %q, want one line", polls, chunk)
+		}
+		if next != start+int64(len(chunk)) {
+			t.Errorf("poll %d: next offset %d, want %d", polls, next, start+int64(len(chunk)))
+		}
+		text.WriteString(chunk)
+		start = next
+		if !more {
+			break
+		}
+	}
+	if text.String() != log {
+		t.Errorf("log = %q, want %q", text.String(), log)
+	}
+	build, err := j.GetBuild(buildUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if build.Building || build.Result != "SUCCESS" {
+		t.Errorf("build is %v with result %q after the whole log was read", build.Building, build.Result)
+	}
+
+	// The offset of a finished build stays at its end
+	chunk, next, more, err := j.GetProgressiveHtml(buildUrl, 0)
+	if err != nil || more || next != int64(len(log)) || !strings.Contains(chunk, "&lt;b&gt;bold&lt;/b&gt;") {
+		t.Errorf("GetProgressiveHtml() = %q, %d, %v, %v", chunk, next, more, err)
+	}
+}
+
+func TestCrumb(t *testing.T) {
+	s := newServer(t)
+	s.RequireCrumb = true
+	j := s.Client()
+	if err := j.DisableJob("team/app"); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.EnableJob("team/app"); err != nil {
+		t.Fatal(err)
+	}
+	// The crumb is fetched once and sent with every POST
+	if n := countRequests(s, "GET", "/crumbIssuer/"); n != 1 {
+		t.Errorf("crumb fetched %d times, want once", n)
+	}
+	if s.Job("team/app").Disabled {
+		t.Error("job is still disabled")
+	}
+
+	// Requests without the crumb are rejected
+	resp, err := http.Post(s.URL+"/job/team/job/app/disable", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("POST without crumb: %s, want 403", resp.Status)
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	s := newServer(t)
+	s.User, s.APIKey = "alice", "secret"
+	if _, err := s.Client().GetJobConfig("team/app"); err != nil {
+		t.Errorf("GetJobConfig() with the API key: %v", err)
+	}
+	j := jenkins.NewJenkins(s.URL, "alice", "wrong")
+	_, err := j.GetJobConfig("team/app")
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("GetJobConfig() with a wrong API key = %v, want 401", err)
+	}
+	if err := j.DisableJob("team/app"); err == nil {
+		t.Error("DisableJob() with a wrong API key succeeded")
+	}
+	if s.Job("team/app").Disabled {
+		t.Error("job was disabled with a wrong API key")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	s := newServer(t)
+	s.Fail(jenkinstest.Failure{Method: "GET", Path: "/job/team/job/app/config.xml", Status: http.StatusServiceUnavailable, Times: 2})
+	j := s.Client()
+	j.Retry = jenkins.RetryPolicy{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, MaxElapsed: time.Second}
+	retries := 0
+	j.OnRetry = func(req *http.Request, resp *http.Response, err error, wait time.Duration) {
+		retries++
+	}
+	config, err := j.GetJobConfig("team/app")
+	if err != nil || config != jenkins.PipelineJobConfig {
+		t.Errorf("GetJobConfig() = %q, %v", config, err)
+	}
+	if retries != 2 {
+		t.Errorf("retried %d times, want 2", retries)
+	}
+
+	// Requests which change anything are not retried
+	s.Fail(jenkinstest.Failure{Method: "POST", Path: "/job/team/job/app/disable", Status: http.StatusServiceUnavailable, Times: 1})
+	if err := j.DisableJob("team/app"); err == nil {
+		t.Error("DisableJob() succeeded on 503")
+	}
+	if n := countRequests(s, "POST", "/job/team/job/app/disable"); n != 1 {
+		t.Errorf("POST sent %d times, want once", n)
+	}
+
+	// Retries stop after MaxElapsed
+	s.Fail(jenkinstest.Failure{Path: "/job/team/job/app/api/json", Status: http.StatusBadGateway})
+	j.Retry.MaxElapsed = 20 * time.Millisecond
+	if _, err := j.GetJob("team/app", 1); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("GetJob() = %v, want 502", err)
+	}
+}
+
+func TestCreateEmptyJob(t *testing.T) {
+	s := newServer(t)
+	j := s.Client()
+	if err := j.CreateEmptyJob("team/services/api"); err != nil {
+		t.Fatal(err)
+	}
+	job := s.Job("team/services/api")
+	if job == nil || job.Folder || job.Config != jenkins.PipelineJobConfig {
+		t.Fatalf("Job() = %+v, want an empty pipeline", job)
+	}
+	if folder := s.Job("team/services"); folder == nil || !folder.Folder {
+		t.Errorf("folder team/services = %+v, want it created", folder)
+	}
+	// Creating a job which exists fails
+	if err := j.CreateEmptyJob("team/app"); err == nil {
+		t.Error("CreateEmptyJob() of an existing job succeeded")
+	}
+}
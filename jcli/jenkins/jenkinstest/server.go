@@ -0,0 +1,662 @@
+// Package jenkinstest provides an in-memory fake of a Jenkins controller for
+// tests of the jenkins client and the commands built on it.
+//
+// The fake implements the parts of the REST API jcli uses: job and folder
+// configs, createItem, doDelete, triggering builds through the queue,
+// progressive console logs, aborting builds, CSRF crumbs and basic auth.
+// Failures can be scripted with Fail to test how clients handle errors.
+package jenkinstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"jcli/jenkins"
+)
+
+const (
+	folderClass   = "com.cloudbees.hudson.plugins.folder.Folder"
+	pipelineClass = "org.jenkinsci.plugins.workflow.job.WorkflowJob"
+
+	// CrumbField is the header the crumb has to be sent in
+	CrumbField = "Jenkins-Crumb"
+	// Crumb is the crumb the server issues
+	Crumb = "fake-crumb"
+)
+
+// Server is a fake Jenkins controller. Set the exported fields before
+// sending requests.
+type Server struct {
+	*httptest.Server
+
+	// User and APIKey are required as basic auth if User is not empty
+	User   string
+	APIKey string
+	// RequireCrumb rejects POST requests without the CSRF crumb. Without it
+	// the server has no crumb issuer, like Jenkins with CSRF protection off.
+	RequireCrumb bool
+	// QueuePolls is how often a queue item is fetched before its build
+	// starts. Zero starts the build on the first poll.
+	QueuePolls int
+
+	mu          sync.Mutex
+	jobs        map[string]*Job
+	queue       []*queueItem
+	nextQueueId int
+	failures    []*Failure
+	requests    []Request
+}
+
+// Job is a job or folder on the fake server
+type Job struct {
+	FullName string
+	Folder   bool
+	Config   string
+	Disabled bool
+	// Log and Result are used for the builds started from now on. Each
+	// progressive log request reveals one more line of the log, and the
+	// build finishes with the result once the whole log was fetched.
+	Log    string
+	Result string
+
+	Builds          []*Build
+	NextBuildNumber int
+}
+
+// Build is a build of a job on the fake server
+type Build struct {
+	Number    int
+	Log       string
+	Result    string
+	Building  bool
+	Timestamp time.Time
+	// Revealed is how much of the log was returned so far
+	Revealed int
+}
+
+type queueItem struct {
+	id         int
+	job        string
+	since      time.Time
+	polls      int
+	cancelled  bool
+	executable *Build
+}
+
+// Failure makes matching requests fail with the status
+type Failure struct {
+	// Method matches any method if empty
+	Method string
+	// Path is a prefix of the request path, like "/job/app/"
+	Path   string
+	Status int
+	// Times is how many requests fail, zero fails all of them
+	Times int
+	// RetryAfter is sent as Retry-After header if not zero
+	RetryAfter time.Duration
+
+	failed int
+}
+
+// Request is a request the server received
+type Request struct {
+	Method string
+	// Path includes the query
+	Path string
+	Body string
+}
+
+// NewServer starts a fake Jenkins controller without any jobs. Close it at
+// the end of the test.
+func NewServer() *Server {
+	s := &Server{jobs: map[string]*Job{}, nextQueueId: 1}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Client returns a client for the server
+func (s *Server) Client() *jenkins.Jenkins {
+	return jenkins.NewJenkins(s.URL, s.User, s.APIKey)
+}
+
+// AddJob creates a pipeline job with the config, and its missing folders
+func (s *Server) AddJob(name, config string) *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name = strings.Trim(name, "/")
+	s.addFolders(jenkins.ParentFolder(name))
+	job := &Job{FullName: name, Config: config, Result: "SUCCESS", NextBuildNumber: 1}
+	s.jobs[name] = job
+	return job
+}
+
+// AddFolder creates the folder and its missing parents
+func (s *Server) AddFolder(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addFolders(strings.Trim(name, "/"))
+}
+
+func (s *Server) addFolders(folder string) {
+	if folder == "" {
+		return
+	}
+	s.addFolders(jenkins.ParentFolder(folder))
+	if _, ok := s.jobs[folder]; !ok {
+		s.jobs[folder] = &Job{FullName: folder, Folder: true, Config: "<" + folderClass + "/>"}
+	}
+}
+
+// AddBuild adds a finished build to the job and returns its number. It
+// panics if the job does not exist.
+func (s *Server) AddBuild(name, log, result string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	build := s.startBuild(s.mustJob(name))
+	build.Log, build.Result, build.Building, build.Revealed = log, result, false, len(log)
+	return build.Number
+}
+
+// SetBuildLog sets the log and result of the builds the job starts from now
+// on. It panics if the job does not exist.
+func (s *Server) SetBuildLog(name, log, result string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.mustJob(name)
+	job.Log, job.Result = log, result
+}
+
+// mustJob returns the job which a test set up, which has to be a job and
+// not a folder
+func (s *Server) mustJob(name string) *Job {
+	job, ok := s.jobs[strings.Trim(name, "/")]
+	if !ok {
+		panic("jenkinstest: no job " + name + ", add it with AddJob first")
+	}
+	if job.Folder {
+		panic("jenkinstest: " + name + " is a folder, which has no builds")
+	}
+	return job
+}
+
+// Job returns a copy of the job, or nil if it does not exist
+func (s *Server) Job(name string) *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[strings.Trim(name, "/")]
+	if !ok {
+		return nil
+	}
+	c := *job
+	c.Builds = make([]*Build, len(job.Builds))
+	for i, b := range job.Builds {
+		build := *b
+		c.Builds[i] = &build
+	}
+	return &c
+}
+
+// Fail makes the requests matching the failure fail
+func (s *Server) Fail(f Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &f)
+}
+
+// Requests returns the requests the server received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.RequestURI(), Body: string(body)})
+
+	if s.User != "" {
+		user, key, ok := r.BasicAuth()
+		if !ok || user != s.User || key != s.APIKey {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Jenkins"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	if f := s.failure(r); f != nil {
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter.Round(time.Second)/time.Second)))
+		}
+		http.Error(w, http.StatusText(f.Status), f.Status)
+		return
+	}
+	if r.URL.Path == "/crumbIssuer/api/json" {
+		if !s.RequireCrumb {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, map[string]string{"_class": "hudson.security.csrf.DefaultCrumbIssuer", "crumbRequestField": CrumbField, "crumb": Crumb})
+		return
+	}
+	if r.Method == http.MethodPost && s.RequireCrumb && r.Header.Get(CrumbField) != Crumb {
+		http.Error(w, "No valid crumb was included in the request", http.StatusForbidden)
+		return
+	}
+
+	// Split the path into the job and what is requested of it
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	var names []string
+	for len(segments) >= 2 && segments[0] == "job" {
+		names = append(names, segments[1])
+		segments = segments[2:]
+	}
+	rest := strings.Join(segments, "/")
+	if len(names) == 0 {
+		s.handleRoot(w, r, rest, body)
+		return
+	}
+	job, ok := s.jobs[strings.Join(names, "/")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.handleJob(w, r, job, segments, body)
+}
+
+// failure returns the first failure matching the request and counts it
+func (s *Server) failure(r *http.Request) *Failure {
+	for _, f := range s.failures {
+		if f.Method != "" && f.Method != r.Method || !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+		if f.Times > 0 && f.failed >= f.Times {
+			continue
+		}
+		f.failed++
+		return f
+	}
+	return nil
+}
+
+func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request, rest string, body []byte) {
+	switch {
+	case rest == "" || rest == "api/json":
+		writeJSON(w, map[string]any{"_class": "hudson.model.Hudson", "jobs": s.children("")})
+	case rest == "createItem" && r.Method == http.MethodPost:
+		s.createItem(w, r, "", body)
+	case rest == "queue/api/json":
+		var items []any
+		for _, item := range s.queue {
+			if item.executable == nil && !item.cancelled {
+				items = append(items, s.queueJSON(item))
+			}
+		}
+		writeJSON(w, map[string]any{"items": items})
+	case rest == "queue/cancelItem" && r.Method == http.MethodPost:
+		id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+		item := s.queueItem(id)
+		if item == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if item.executable == nil {
+			item.cancelled = true
+		}
+	case strings.HasPrefix(rest, "queue/item/") && strings.HasSuffix(rest, "/api/json"):
+		id, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rest, "queue/item/"), "/api/json"))
+		item := s.queueItem(id)
+		if item == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if !item.cancelled && item.executable == nil {
+			item.polls++
+			if item.polls > s.QueuePolls {
+				if job, ok := s.jobs[item.job]; ok {
+					item.executable = s.startBuild(job)
+				}
+			}
+		}
+		writeJSON(w, s.queueJSON(item))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request, job *Job, segments []string, body []byte) {
+	rest := strings.Join(segments, "/")
+	switch {
+	case rest == "api/json":
+		writeJSON(w, s.jobJSON(job))
+	case rest == "config.xml" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "application/xml")
+		io.WriteString(w, job.Config)
+	case rest == "config.xml" && r.Method == http.MethodPost:
+		job.Config = string(body)
+	case rest == "createItem" && job.Folder && r.Method == http.MethodPost:
+		s.createItem(w, r, job.FullName, body)
+	case rest == "doDelete" && r.Method == http.MethodPost:
+		for name := range s.jobs {
+			if name == job.FullName || strings.HasPrefix(name, job.FullName+"/") {
+				delete(s.jobs, name)
+			}
+		}
+	case (rest == "enable" || rest == "disable") && r.Method == http.MethodPost && !job.Folder:
+		job.Disabled = rest == "disable"
+	case rest == "confirmRename" && r.Method == http.MethodPost:
+		s.rename(w, r, job)
+	case (rest == "build" || rest == "buildWithParameters") && r.Method == http.MethodPost:
+		if job.Folder || job.Disabled {
+			http.Error(w, "Cannot build "+job.FullName, http.StatusConflict)
+			return
+		}
+		item := &queueItem{id: s.nextQueueId, job: job.FullName, since: time.Now()}
+		s.nextQueueId++
+		s.queue = append(s.queue, item)
+		w.Header().Set("Location", s.URL+"/queue/item/"+strconv.Itoa(item.id)+"/")
+		w.WriteHeader(http.StatusCreated)
+	case len(segments) >= 2:
+		build := s.build(job, segments[0])
+		if build == nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.handleBuild(w, r, job, build, strings.Join(segments[1:], "/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleBuild(w http.ResponseWriter, r *http.Request, job *Job, build *Build, rest string) {
+	switch {
+	case rest == "api/json":
+		writeJSON(w, s.buildJSON(job, build))
+	case rest == "logText/progressiveText" || rest == "logText/progressiveHtml":
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		start = min(max(start, 0), len(build.Log))
+		// Reveal one more line on each request, like a running build
+		if build.Revealed < len(build.Log) {
+			next := strings.IndexByte(build.Log[build.Revealed:], '\n')
+			if next < 0 {
+				build.Revealed = len(build.Log)
+			} else {
+				build.Revealed += next + 1
+			}
+		}
+		if build.Revealed == len(build.Log) && build.Building {
+			build.Building = false
+		}
+		text := build.Log[min(start, build.Revealed):build.Revealed]
+		if strings.HasSuffix(rest, "Html") {
+			text = html.EscapeString(text)
+		}
+		w.Header().Set("X-Text-Size", strconv.Itoa(build.Revealed))
+		if build.Building {
+			w.Header().Set("X-More-Data", "true")
+		}
+		io.WriteString(w, text)
+	case rest == "consoleText":
+		io.WriteString(w, build.Log[:build.Revealed])
+	case rest == "stop" && r.Method == http.MethodPost:
+		if build.Building {
+			build.Log = build.Log[:build.Revealed] + "Aborted by " + s.User + "\nFinished: ABORTED\n"
+			build.Result = "ABORTED"
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// createItem creates the job named in the query inside the folder
+func (s *Server) createItem(w http.ResponseWriter, r *http.Request, folder string, body []byte) {
+	name := r.URL.Query().Get("name")
+	fullName := path.Join(folder, name)
+	if name == "" || strings.Contains(name, "/") {
+		http.Error(w, "Invalid name", http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.jobs[fullName]; ok {
+		http.Error(w, "A job already exists with the name "+name, http.StatusBadRequest)
+		return
+	}
+	config := string(body)
+	if r.URL.Query().Get("mode") == "copy" {
+		from, ok := s.jobs[strings.Trim(r.URL.Query().Get("from"), "/")]
+		if !ok {
+			http.Error(w, "No such job: "+r.URL.Query().Get("from"), http.StatusBadRequest)
+			return
+		}
+		config = from.Config
+	}
+	s.jobs[fullName] = &Job{
+		FullName:        fullName,
+		Folder:          strings.Contains(config, folderClass),
+		Config:          config,
+		Result:          "SUCCESS",
+		NextBuildNumber: 1,
+	}
+}
+
+// rename renames the job and the jobs inside it to the new name in the query
+func (s *Server) rename(w http.ResponseWriter, r *http.Request, job *Job) {
+	newName := r.URL.Query().Get("newName")
+	if newName == "" || strings.Contains(newName, "/") {
+		http.Error(w, "Invalid name", http.StatusBadRequest)
+		return
+	}
+	oldName := job.FullName
+	fullName := path.Join(jenkins.ParentFolder(oldName), newName)
+	if _, ok := s.jobs[fullName]; ok {
+		http.Error(w, "A job already exists with the name "+newName, http.StatusBadRequest)
+		return
+	}
+	var moved []*Job
+	for name, j := range s.jobs {
+		if name == oldName || strings.HasPrefix(name, oldName+"/") {
+			delete(s.jobs, name)
+			moved = append(moved, j)
+		}
+	}
+	for _, j := range moved {
+		j.FullName = fullName + strings.TrimPrefix(j.FullName, oldName)
+		s.jobs[j.FullName] = j
+	}
+	for _, item := range s.queue {
+		if item.job == oldName {
+			item.job = fullName
+		}
+	}
+}
+
+// startBuild starts the next build of the job
+func (s *Server) startBuild(job *Job) *Build {
+	build := &Build{
+		Number:    job.NextBuildNumber,
+		Log:       job.Log,
+		Result:    job.Result,
+		Building:  true,
+		Timestamp: time.Now(),
+	}
+	job.NextBuildNumber++
+	job.Builds = append(job.Builds, build)
+	return build
+}
+
+// build returns the build with the number or permalink
+func (s *Server) build(job *Job, ref string) *Build {
+	for i := len(job.Builds) - 1; i >= 0; i-- {
+		b := job.Builds[i]
+		switch ref {
+		case strconv.Itoa(b.Number), "lastBuild":
+			return b
+		case "lastCompletedBuild":
+			if !b.Building {
+				return b
+			}
+		case "lastSuccessfulBuild", "lastFailedBuild":
+			if !b.Building && (b.Result == "SUCCESS") == (ref == "lastSuccessfulBuild") {
+				return b
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) queueItem(id int) *queueItem {
+	for _, item := range s.queue {
+		if item.id == id {
+			return item
+		}
+	}
+	return nil
+}
+
+func (s *Server) jobUrl(name string) string {
+	return jenkins.NewJenkins(s.URL, "", "").JobUrl(name) + "/"
+}
+
+func (s *Server) children(folder string) []map[string]any {
+	var names []string
+	for name := range s.jobs {
+		if jenkins.ParentFolder(name) == folder {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	children := []map[string]any{}
+	for _, name := range names {
+		job := s.jobs[name]
+		children = append(children, map[string]any{
+			"_class":   jobClass(job),
+			"name":     path.Base(name),
+			"fullName": name,
+			"url":      s.jobUrl(name),
+			"color":    jobColor(job),
+		})
+	}
+	return children
+}
+
+func (s *Server) jobJSON(job *Job) map[string]any {
+	v := map[string]any{
+		"_class":   jobClass(job),
+		"name":     path.Base(job.FullName),
+		"fullName": job.FullName,
+		"url":      s.jobUrl(job.FullName),
+	}
+	if job.Folder {
+		v["jobs"] = s.children(job.FullName)
+		return v
+	}
+	builds := []any{}
+	for i := len(job.Builds) - 1; i >= 0; i-- {
+		builds = append(builds, s.buildJSON(job, job.Builds[i]))
+	}
+	inQueue := false
+	for _, item := range s.queue {
+		inQueue = inQueue || item.job == job.FullName && item.executable == nil && !item.cancelled
+	}
+	v["color"] = jobColor(job)
+	v["buildable"] = !job.Disabled
+	v["disabled"] = job.Disabled
+	v["inQueue"] = inQueue
+	v["nextBuildNumber"] = job.NextBuildNumber
+	v["builds"] = builds
+	if b := s.build(job, "lastBuild"); b != nil {
+		v["lastBuild"] = s.buildJSON(job, b)
+	}
+	if b := s.build(job, "lastCompletedBuild"); b != nil {
+		v["lastCompletedBuild"] = s.buildJSON(job, b)
+	}
+	return v
+}
+
+func (s *Server) buildJSON(job *Job, build *Build) map[string]any {
+	v := map[string]any{
+		"_class":            "org.jenkinsci.plugins.workflow.job.WorkflowRun",
+		"number":            build.Number,
+		"url":               s.jobUrl(job.FullName) + strconv.Itoa(build.Number) + "/",
+		"building":          build.Building,
+		"result":            nil,
+		"timestamp":         build.Timestamp.UnixMilli(),
+		"duration":          0,
+		"estimatedDuration": -1,
+	}
+	if !build.Building {
+		v["result"] = build.Result
+	}
+	return v
+}
+
+func (s *Server) queueJSON(item *queueItem) map[string]any {
+	v := map[string]any{
+		"_class":       "hudson.model.Queue$WaitingItem",
+		"id":           item.id,
+		"url":          "queue/item/" + strconv.Itoa(item.id) + "/",
+		"inQueueSince": item.since.UnixMilli(),
+		"why":          "Waiting for next available executor",
+		"buildable":    true,
+		"cancelled":    item.cancelled,
+		"task": map[string]any{
+			"name": path.Base(item.job),
+			"url":  s.jobUrl(item.job),
+		},
+	}
+	if item.cancelled {
+		v["_class"], v["why"] = "hudson.model.Queue$CancelledItem", nil
+	}
+	if item.executable != nil {
+		v["_class"], v["why"] = "hudson.model.Queue$LeftItem", nil
+		v["executable"] = map[string]any{
+			"number": item.executable.Number,
+			"url":    s.jobUrl(item.job) + strconv.Itoa(item.executable.Number) + "/",
+		}
+	}
+	return v
+}
+
+func jobClass(job *Job) string {
+	if job.Folder {
+		return folderClass
+	}
+	return pipelineClass
+}
+
+func jobColor(job *Job) string {
+	if job.Folder {
+		return ""
+	}
+	if job.Disabled {
+		return "disabled"
+	}
+	if len(job.Builds) == 0 {
+		return "notbuilt"
+	}
+	last := job.Builds[len(job.Builds)-1]
+	color := map[string]string{"SUCCESS": "blue", "UNSTABLE": "yellow", "FAILURE": "red", "ABORTED": "aborted"}[last.Result]
+	if color == "" {
+		color = "notbuilt"
+	}
+	if last.Building {
+		color += "_anime"
+	}
+	return color
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+	}
+}
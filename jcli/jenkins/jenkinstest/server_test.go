@@ -0,0 +1,150 @@
+package jenkinstest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// get sends a GET request to the server and returns the response body
+func get(t *testing.T, s *Server, path string) (*http.Response, string) {
+	t.Helper()
+	resp, err := http.Get(s.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestAddBuild(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddJob("team/app", "<flow-definition/>")
+	if n := s.AddBuild("team/app", "ok\n", "SUCCESS"); n != 1 {
+		t.Errorf("AddBuild() = %d, want 1", n)
+	}
+	if n := s.AddBuild("/team/app/", "failed\n", "FAILURE"); n != 2 {
+		t.Errorf("AddBuild() = %d, want 2", n)
+	}
+	job := s.Job("team/app")
+	if job == nil || len(job.Builds) != 2 || job.NextBuildNumber != 3 {
+		t.Fatalf("Job() = %+v, want two builds", job)
+	}
+	// Job returns a copy
+	job.Builds[0].Result = "ABORTED"
+	if s.Job("team/app").Builds[0].Result != "SUCCESS" {
+		t.Error("changing the copy of Job() changed the server")
+	}
+	if folder := s.Job("team"); folder == nil || !folder.Folder {
+		t.Errorf("Job(team) = %+v, want the folder", folder)
+	}
+
+	for path, want := range map[string]string{
+		"/job/team/job/app/lastSuccessfulBuild/consoleText": "ok\n",
+		"/job/team/job/app/lastFailedBuild/consoleText":     "failed\n",
+		"/job/team/job/app/lastBuild/consoleText":           "failed\n",
+	} {
+		if _, body := get(t, s, path); body != want {
+			t.Errorf("GET %s = %q, want %q", path, body, want)
+		}
+	}
+}
+
+func TestAddBuildUnknownJob(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddFolder("team")
+	for _, name := range []string{"missing", "team"} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(r.(string), name) {
+					t.Errorf("AddBuild(%s) panicked with %v, want a message naming the job", name, r)
+				}
+			}()
+			s.AddBuild(name, "", "SUCCESS")
+		}()
+	}
+}
+
+func TestFail(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddJob("app", "<flow-definition/>")
+	s.Fail(Failure{Method: "GET", Path: "/job/app/", Status: http.StatusServiceUnavailable, Times: 2, RetryAfter: 3 * time.Second})
+	for i := 0; i < 2; i++ {
+		resp, _ := get(t, s, "/job/app/config.xml")
+		if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "3" {
+			t.Errorf("request %d: %s with Retry-After %q, want 503 with 3", i, resp.Status, resp.Header.Get("Retry-After"))
+		}
+	}
+	if resp, body := get(t, s, "/job/app/config.xml"); resp.StatusCode != http.StatusOK || body != "<flow-definition/>" {
+		t.Errorf("request after the failures: %s %q", resp.Status, body)
+	}
+	// Failures are counted per request, all requests are recorded
+	if n := len(s.Requests()); n != 3 {
+		t.Errorf("Requests() has %d requests, want 3", n)
+	}
+}
+
+func TestBuildLifecycle(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddJob("app", "<flow-definition/>")
+	s.SetBuildLog("app", "one\ntwo\n", "UNSTABLE")
+	resp, err := http.Post(s.URL+"/job/app/build?delay=0sec", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location := resp.Header.Get("Location")
+	if resp.StatusCode != http.StatusCreated || !strings.HasPrefix(location, s.URL+"/queue/item/") {
+		t.Fatalf("build: %s with location %q", resp.Status, location)
+	}
+	if _, body := get(t, s, "/queue/item/1/api/json"); !strings.Contains(body, `"number":1`) {
+		t.Errorf("queue item did not start a build: %s", body)
+	}
+
+	// Each log request reveals one more line, then the build finishes
+	for i, want := range []string{"one\n", "two\n"} {
+		resp, body := get(t, s, "/job/app/1/logText/progressiveText?start=0")
+		if !strings.HasSuffix(body, want) {
+			t.Errorf("request %d: log %q, want it to end with %q", i, body, want)
+		}
+		if more := resp.Header.Get("X-More-Data") == "true"; more != (i == 0) {
+			t.Errorf("request %d: X-More-Data %v", i, more)
+		}
+	}
+	if build := s.Job("app").Builds[0]; build.Building || build.Result != "UNSTABLE" {
+		t.Errorf("build is %v with result %q, want finished as UNSTABLE", build.Building, build.Result)
+	}
+	if job := s.Job("app"); job.Log != "one\ntwo\n" {
+		t.Errorf("job log = %q", job.Log)
+	}
+}
+
+func TestRename(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddJob("team/app", "<flow-definition/>")
+	resp, err := http.Post(s.URL+"/job/team/confirmRename?newName=squad", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("rename: %s", resp.Status)
+	}
+	if s.Job("team") != nil || s.Job("team/app") != nil {
+		t.Error("old names still exist")
+	}
+	if job := s.Job("squad/app"); job == nil || job.FullName != "squad/app" {
+		t.Errorf("Job(squad/app) = %+v", job)
+	}
+}
@@ -0,0 +1,571 @@
+package jenkins
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+const (
+	buildDiscarderProperty     = "jenkins.model.BuildDiscarderProperty"
+	disableConcurrentProperty  = "org.jenkinsci.plugins.workflow.job.properties.DisableConcurrentBuildsJobProperty"
+	pipelineTriggersProperty   = "org.jenkinsci.plugins.workflow.job.properties.PipelineTriggersJobProperty"
+	parametersProperty         = "hudson.model.ParametersDefinitionProperty"
+	cronTrigger                = "hudson.triggers.TimerTrigger"
+	pollSCMTrigger             = "hudson.triggers.SCMTrigger"
+	stringParameterDefinition  = "hudson.model.StringParameterDefinition"
+	choiceParameterDefinition  = "hudson.model.ChoiceParameterDefinition"
+	booleanParameterDefinition = "hudson.model.BooleanParameterDefinition"
+)
+
+// ErrNoPipelineDefinition is returned when changing pipeline settings of a
+// job which is no pipeline job with an inline script
+var ErrNoPipelineDefinition = errors.New("job has no inline pipeline definition")
+
+// ParameterType is the kind of a build parameter
+type ParameterType string
+
+const (
+	StringParameter  ParameterType = "string"
+	ChoiceParameter  ParameterType = "choice"
+	BooleanParameter ParameterType = "boolean"
+)
+
+// Parameter is the definition of a build parameter. The first choice of a
+// choice parameter is its default.
+type Parameter struct {
+	Type         ParameterType
+	Name         string
+	Description  string
+	DefaultValue string
+	Choices      []string
+}
+
+// JobConfig is the parsed config.xml of a job, which can be changed and
+// written back without editing the XML by hand. Only the changed elements
+// are formatted, the rest of the config is written as it was read.
+type JobConfig struct {
+	doc *etree.Document
+	// decl is the original XML declaration, Jenkins writes XML 1.1 which
+	// the parser does not read
+	decl string
+}
+
+// emptyText marks the elements written as <tag></tag> while parsing, which
+// would be written as <tag/> otherwise
+const emptyText = "\uE000"
+
+// ParseJobConfig parses the config.xml of a job
+func ParseJobConfig(config string) (*JobConfig, error) {
+	decl := xmlDeclRegexp.FindString(config)
+	config = strings.ReplaceAll(config[len(decl):], "></", ">"+emptyText+"</")
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(config); err != nil {
+		return nil, err
+	}
+	if doc.Root() == nil {
+		return nil, errors.New("config has no root element")
+	}
+	var clearEmptyText func(e *etree.Element)
+	clearEmptyText = func(e *etree.Element) {
+		for _, t := range e.Child {
+			switch t := t.(type) {
+			case *etree.CharData:
+				if t.Data == emptyText {
+					t.Data = ""
+				}
+			case *etree.Element:
+				clearEmptyText(t)
+			}
+		}
+	}
+	clearEmptyText(doc.Root())
+	return &JobConfig{doc: doc, decl: decl}, nil
+}
+
+// String returns the config.xml with its original XML declaration
+func (c *JobConfig) String() (string, error) {
+	config, err := c.doc.WriteToString()
+	if err != nil {
+		return "", err
+	}
+	return c.decl + config, nil
+}
+
+// isPipeline reports whether the config is the one of a pipeline job
+func (c *JobConfig) isPipeline() bool {
+	return c.doc.Root().Tag == "flow-definition"
+}
+
+// element returns the child of the parent with the tag and creates it if it
+// is missing
+func element(parent *etree.Element, tag string) *etree.Element {
+	if e := parent.SelectElement(tag); e != nil {
+		return e
+	}
+	return addElement(parent, etree.NewElement(tag))
+}
+
+// addElement adds the new element as the last child of the parent, indented
+// like Jenkins writes configs
+func addElement(parent, e *etree.Element) *etree.Element {
+	level := depth(parent)
+	indent(e, level+1)
+	// The indentation of the closing tag of the parent stays last
+	if n := len(parent.Child); n > 0 && isIndentation(parent.Child[n-1]) {
+		parent.InsertChildAt(n-1, etree.NewText(indentation(level+1)))
+		parent.InsertChildAt(n, e)
+		return e
+	}
+	parent.AddChild(etree.NewText(indentation(level + 1)))
+	parent.AddChild(e)
+	parent.AddChild(etree.NewText(indentation(level)))
+	return e
+}
+
+// replaceElement puts the new element in the place of the old one
+func replaceElement(old, e *etree.Element) {
+	parent := old.Parent()
+	indent(e, depth(old))
+	parent.InsertChildAt(old.Index(), e)
+	parent.RemoveChild(old)
+}
+
+// removeElement removes the children of the parent with the tag
+func removeElement(parent *etree.Element, tag string) {
+	for _, e := range parent.SelectElements(tag) {
+		removeChild(e)
+	}
+}
+
+// removeChild removes the element from its parent along with its
+// indentation
+func removeChild(e *etree.Element) {
+	parent := e.Parent()
+	i := e.Index()
+	parent.RemoveChildAt(i)
+	if i > 0 && isIndentation(parent.Child[i-1]) {
+		parent.RemoveChildAt(i - 1)
+	}
+	// Empty elements are written as <tag/>
+	if len(parent.ChildElements()) == 0 {
+		for i := len(parent.Child) - 1; i >= 0; i-- {
+			if isIndentation(parent.Child[i]) {
+				parent.RemoveChildAt(i)
+			}
+		}
+	}
+}
+
+// indent puts the children of the new element on lines of their own, the
+// element being at the depth
+func indent(e *etree.Element, level int) {
+	children := e.ChildElements()
+	if len(children) == 0 {
+		return
+	}
+	for _, child := range children {
+		e.InsertChildAt(child.Index(), etree.NewText(indentation(level+1)))
+		indent(child, level+1)
+	}
+	e.AddChild(etree.NewText(indentation(level)))
+}
+
+// depth returns how deep the element is nested, the root is at depth 0
+func depth(e *etree.Element) int {
+	level := 0
+	// The document itself is the parent of the root
+	for p := e.Parent(); p != nil && p.Parent() != nil; p = p.Parent() {
+		level++
+	}
+	return level
+}
+
+// indentation returns the line break and indentation before an element at
+// the depth
+func indentation(level int) string {
+	return "\n" + strings.Repeat("  ", level)
+}
+
+// isIndentation reports whether the token is whitespace between elements
+func isIndentation(t etree.Token) bool {
+	text, ok := t.(*etree.CharData)
+	return ok && text.Data != "" && strings.TrimSpace(text.Data) == ""
+}
+
+// setText changes the text of the element. Like Jenkins, elements with an
+// empty text are written as <tag></tag>.
+func setText(e *etree.Element, text string) {
+	e.SetText(text)
+	if text == "" && len(e.Child) == 0 {
+		e.AddChild(etree.NewText(""))
+	}
+}
+
+// properties returns the properties element of the job and creates it if
+// it is missing
+func (c *JobConfig) properties() *etree.Element {
+	return element(c.doc.Root(), "properties")
+}
+
+// Description returns the description of the job
+func (c *JobConfig) Description() string {
+	if e := c.doc.Root().SelectElement("description"); e != nil {
+		return e.Text()
+	}
+	return ""
+}
+
+// SetDescription changes the description of the job
+func (c *JobConfig) SetDescription(description string) {
+	setText(element(c.doc.Root(), "description"), description)
+}
+
+// KeepBuilds returns how many builds are kept, or 0 if their number is not
+// limited
+func (c *JobConfig) KeepBuilds() int {
+	e := c.doc.Root().FindElement("properties/" + buildDiscarderProperty + "/strategy/numToKeep")
+	if e == nil {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(e.Text()))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// SetKeepBuilds discards all but the last n builds, or removes the limit of
+// builds if n is 0. The other limits of the build discarder, like the days
+// builds are kept, stay as they are.
+func (c *JobConfig) SetKeepBuilds(n int) {
+	strategy := c.doc.Root().FindElement("properties/" + buildDiscarderProperty + "/strategy")
+	if strategy != nil {
+		numToKeep := -1
+		if n > 0 {
+			numToKeep = n
+		}
+		element(strategy, "numToKeep").SetText(strconv.Itoa(numToKeep))
+		if n <= 0 && !hasLimit(strategy) {
+			removeElement(c.properties(), buildDiscarderProperty)
+		}
+		return
+	}
+	if n <= 0 {
+		return
+	}
+	discarder := etree.NewElement(buildDiscarderProperty)
+	strategy = discarder.CreateElement("strategy")
+	strategy.CreateAttr("class", "hudson.tasks.LogRotator")
+	strategy.CreateElement("daysToKeep").SetText("-1")
+	strategy.CreateElement("numToKeep").SetText(strconv.Itoa(n))
+	strategy.CreateElement("artifactDaysToKeep").SetText("-1")
+	strategy.CreateElement("artifactNumToKeep").SetText("-1")
+	addElement(c.properties(), discarder)
+}
+
+// hasLimit reports whether the build discarder strategy limits anything,
+// limits of -1 or none are unset
+func hasLimit(strategy *etree.Element) bool {
+	for _, e := range strategy.ChildElements() {
+		if n, err := strconv.Atoi(strings.TrimSpace(e.Text())); err == nil && n > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ConcurrentBuilds reports whether builds of the job may run at the same
+// time
+func (c *JobConfig) ConcurrentBuilds() bool {
+	if c.isPipeline() {
+		return c.doc.Root().FindElement("properties/"+disableConcurrentProperty) == nil
+	}
+	e := c.doc.Root().SelectElement("concurrentBuild")
+	return e != nil && strings.TrimSpace(e.Text()) == "true"
+}
+
+// SetConcurrentBuilds allows or prevents builds of the job running at the
+// same time
+func (c *JobConfig) SetConcurrentBuilds(allowed bool) {
+	if !c.isPipeline() {
+		// Freestyle jobs and other projects have a flag instead
+		element(c.doc.Root(), "concurrentBuild").SetText(strconv.FormatBool(allowed))
+		return
+	}
+	properties := c.properties()
+	if allowed {
+		removeElement(properties, disableConcurrentProperty)
+	} else if properties.SelectElement(disableConcurrentProperty) == nil {
+		property := etree.NewElement(disableConcurrentProperty)
+		property.CreateElement("abortPrevious").SetText("false")
+		addElement(properties, property)
+	}
+}
+
+// triggers returns the element of the triggers, which pipeline jobs keep in
+// a property. It is only created if create is set.
+func (c *JobConfig) triggers(create bool) *etree.Element {
+	if !c.isPipeline() {
+		if !create {
+			return c.doc.Root().SelectElement("triggers")
+		}
+		return element(c.doc.Root(), "triggers")
+	}
+	if !create {
+		return c.doc.Root().FindElement("properties/" + pipelineTriggersProperty + "/triggers")
+	}
+	return element(element(c.properties(), pipelineTriggersProperty), "triggers")
+}
+
+// trigger returns the spec of the trigger, or an empty string if the job
+// has none
+func (c *JobConfig) trigger(class string) string {
+	triggers := c.triggers(false)
+	if triggers == nil {
+		return ""
+	}
+	if spec := triggers.FindElement(class + "/spec"); spec != nil {
+		return spec.Text()
+	}
+	return ""
+}
+
+// setTrigger changes the spec of the trigger, an empty spec removes it
+func (c *JobConfig) setTrigger(class, spec string) {
+	if spec == "" {
+		triggers := c.triggers(false)
+		if triggers == nil {
+			return
+		}
+		removeElement(triggers, class)
+		// Do not leave an empty property behind
+		if c.isPipeline() && len(triggers.ChildElements()) == 0 {
+			removeElement(c.properties(), pipelineTriggersProperty)
+		}
+		return
+	}
+	triggers := c.triggers(true)
+	if trigger := triggers.SelectElement(class); trigger != nil {
+		element(trigger, "spec").SetText(spec)
+		return
+	}
+	trigger := etree.NewElement(class)
+	trigger.CreateElement("spec").SetText(spec)
+	if class == pollSCMTrigger {
+		trigger.CreateElement("ignorePostCommitHooks").SetText("false")
+	}
+	addElement(triggers, trigger)
+}
+
+// CronTrigger returns the cron spec the job is built periodically with
+func (c *JobConfig) CronTrigger() string {
+	return c.trigger(cronTrigger)
+}
+
+// SetCronTrigger builds the job periodically with the cron spec, like
+// "H 2 * * *". An empty spec removes the trigger.
+func (c *JobConfig) SetCronTrigger(spec string) {
+	c.setTrigger(cronTrigger, spec)
+}
+
+// PollSCMTrigger returns the cron spec the SCM of the job is polled with
+func (c *JobConfig) PollSCMTrigger() string {
+	return c.trigger(pollSCMTrigger)
+}
+
+// SetPollSCMTrigger polls the SCM of the job with the cron spec and builds
+// it on changes. An empty spec removes the trigger.
+func (c *JobConfig) SetPollSCMTrigger(spec string) {
+	c.setTrigger(pollSCMTrigger, spec)
+}
+
+// Sandbox reports whether the pipeline script runs in the Groovy sandbox
+func (c *JobConfig) Sandbox() (bool, error) {
+	definition := c.doc.FindElement("//definition/script/..")
+	if definition == nil {
+		return false, ErrNoPipelineDefinition
+	}
+	e := definition.SelectElement("sandbox")
+	return e != nil && strings.TrimSpace(e.Text()) == "true", nil
+}
+
+// SetSandbox runs the pipeline script in the Groovy sandbox or, if not
+// enabled, with the permissions of the job, which needs script approval
+func (c *JobConfig) SetSandbox(enabled bool) error {
+	definition := c.doc.FindElement("//definition/script/..")
+	if definition == nil {
+		return ErrNoPipelineDefinition
+	}
+	element(definition, "sandbox").SetText(strconv.FormatBool(enabled))
+	return nil
+}
+
+// SetScript replaces the pipeline script
+func (c *JobConfig) SetScript(script string) error {
+	e := c.doc.FindElement("//definition/script")
+	if e == nil {
+		return ErrNoPipelineScript
+	}
+	e.SetText(script)
+	return nil
+}
+
+// parameterDefinitions returns the element of the parameter definitions.
+// It is only created if create is set.
+func (c *JobConfig) parameterDefinitions(create bool) *etree.Element {
+	if !create {
+		return c.doc.Root().FindElement("properties/" + parametersProperty + "/parameterDefinitions")
+	}
+	return element(element(c.properties(), parametersProperty), "parameterDefinitions")
+}
+
+// Parameters returns the string, choice and boolean parameters of the job.
+// Parameters of other types are left out.
+func (c *JobConfig) Parameters() []Parameter {
+	definitions := c.parameterDefinitions(false)
+	if definitions == nil {
+		return nil
+	}
+	var params []Parameter
+	for _, e := range definitions.ChildElements() {
+		p := Parameter{
+			Name:        elementText(e, "name"),
+			Description: elementText(e, "description"),
+		}
+		switch e.Tag {
+		case stringParameterDefinition:
+			p.Type, p.DefaultValue = StringParameter, elementText(e, "defaultValue")
+		case booleanParameterDefinition:
+			p.Type, p.DefaultValue = BooleanParameter, elementText(e, "defaultValue")
+		case choiceParameterDefinition:
+			p.Type = ChoiceParameter
+			for _, choice := range e.FindElements("choices//string") {
+				p.Choices = append(p.Choices, choice.Text())
+			}
+			if len(p.Choices) > 0 {
+				p.DefaultValue = p.Choices[0]
+			}
+		default:
+			continue
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+// elementText returns the text of the child with the tag, or an empty
+// string if it is missing
+func elementText(parent *etree.Element, tag string) string {
+	if e := parent.SelectElement(tag); e != nil {
+		return e.Text()
+	}
+	return ""
+}
+
+// parameter returns the definition of the parameter with the name
+func parameter(definitions *etree.Element, name string) *etree.Element {
+	for _, e := range definitions.ChildElements() {
+		if n := e.SelectElement("name"); n != nil && n.Text() == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Validate checks that the parameter can be added to a job
+func (p Parameter) Validate() error {
+	_, err := p.definition()
+	return err
+}
+
+// definition returns the element defining the parameter in the config
+func (p Parameter) definition() (*etree.Element, error) {
+	if p.Name == "" {
+		return nil, errors.New("parameter has no name")
+	}
+	e := etree.NewElement("")
+	switch p.Type {
+	case StringParameter:
+		e.Tag = stringParameterDefinition
+		e.CreateElement("name").SetText(p.Name)
+		setText(e.CreateElement("description"), p.Description)
+		setText(e.CreateElement("defaultValue"), p.DefaultValue)
+		e.CreateElement("trim").SetText("false")
+	case BooleanParameter:
+		value := false
+		if p.DefaultValue != "" {
+			var err error
+			if value, err = strconv.ParseBool(strings.TrimSpace(p.DefaultValue)); err != nil {
+				return nil, fmt.Errorf("default value of boolean parameter %s is not true or false: %s", p.Name, p.DefaultValue)
+			}
+		}
+		e.Tag = booleanParameterDefinition
+		e.CreateElement("name").SetText(p.Name)
+		setText(e.CreateElement("description"), p.Description)
+		e.CreateElement("defaultValue").SetText(strconv.FormatBool(value))
+	case ChoiceParameter:
+		if len(p.Choices) == 0 {
+			return nil, fmt.Errorf("choice parameter %s has no choices", p.Name)
+		}
+		e.Tag = choiceParameterDefinition
+		e.CreateElement("name").SetText(p.Name)
+		setText(e.CreateElement("description"), p.Description)
+		choices := e.CreateElement("choices")
+		choices.CreateAttr("class", "java.util.Arrays$ArrayList")
+		array := choices.CreateElement("a")
+		array.CreateAttr("class", "string-array")
+		for _, choice := range p.Choices {
+			array.CreateElement("string").SetText(choice)
+		}
+	default:
+		return nil, fmt.Errorf("unknown parameter type %q, use string, choice or boolean", p.Type)
+	}
+	return e, nil
+}
+
+// SetParameter adds the parameter or replaces the one with the same name,
+// keeping its position. A replaced parameter keeps its description unless
+// the parameter has one.
+func (c *JobConfig) SetParameter(p Parameter) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	definitions := c.parameterDefinitions(true)
+	old := parameter(definitions, p.Name)
+	if old != nil && p.Description == "" {
+		p.Description = elementText(old, "description")
+	}
+	e, err := p.definition()
+	if err != nil {
+		return err
+	}
+	if old != nil {
+		replaceElement(old, e)
+	} else {
+		addElement(definitions, e)
+	}
+	return nil
+}
+
+// RemoveParameter removes the parameter with the name and reports whether
+// the job had it
+func (c *JobConfig) RemoveParameter(name string) bool {
+	definitions := c.parameterDefinitions(false)
+	if definitions == nil {
+		return false
+	}
+	e := parameter(definitions, name)
+	if e == nil {
+		return false
+	}
+	removeChild(e)
+	// Jobs without parameters have no property
+	if len(definitions.ChildElements()) == 0 {
+		removeElement(c.properties(), parametersProperty)
+	}
+	return true
+}
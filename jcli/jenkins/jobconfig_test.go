@@ -0,0 +1,291 @@
+package jenkins
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readConfig returns the config.xml of a pipeline job as Jenkins writes it
+func readConfig(t *testing.T) string {
+	t.Helper()
+	data, err := os.ReadFile("testdata/pipeline-config.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// editConfig parses the config, applies the edit and returns the result
+func editConfig(t *testing.T, config string, edit func(c *JobConfig) error) string {
+	t.Helper()
+	c, err := ParseJobConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := edit(c); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := c.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return updated
+}
+
+// replace replaces old in the config once and fails if it is missing
+func replace(t *testing.T, config, old, new string) string {
+	t.Helper()
+	if !strings.Contains(config, old) {
+		t.Fatalf("config does not contain %q", old)
+	}
+	return strings.Replace(config, old, new, 1)
+}
+
+func TestJobConfigUnchanged(t *testing.T) {
+	config := readConfig(t)
+	got := editConfig(t, config, func(c *JobConfig) error { return nil })
+	if got != config {
+		t.Errorf("config changed without edits:\n%s", got)
+	}
+}
+
+func TestJobConfigGetters(t *testing.T) {
+	c, err := ParseJobConfig(readConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.Description(), `Builds the app & deploys it to "staging"`; got != want {
+		t.Errorf("Description() = %q, want %q", got, want)
+	}
+	if got := c.KeepBuilds(); got != 10 {
+		t.Errorf("KeepBuilds() = %d, want 10", got)
+	}
+	if c.ConcurrentBuilds() {
+		t.Error("ConcurrentBuilds() = true, want false")
+	}
+	if got := c.CronTrigger(); got != "H 2 * * *" {
+		t.Errorf("CronTrigger() = %q, want %q", got, "H 2 * * *")
+	}
+	if got := c.PollSCMTrigger(); got != "" {
+		t.Errorf("PollSCMTrigger() = %q, want none", got)
+	}
+	if sandbox, err := c.Sandbox(); err != nil || !sandbox {
+		t.Errorf("Sandbox() = %v, %v, want true", sandbox, err)
+	}
+	want := []Parameter{
+		{Type: StringParameter, Name: "VERSION", Description: "Version to deploy", DefaultValue: "latest"},
+		{Type: ChoiceParameter, Name: "ENV", DefaultValue: "staging", Choices: []string{"staging", "prod"}},
+	}
+	if got := c.Parameters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Parameters() = %+v, want %+v", got, want)
+	}
+}
+
+func TestJobConfigSetKeepBuilds(t *testing.T) {
+	config := readConfig(t)
+	got := editConfig(t, config, func(c *JobConfig) error {
+		c.SetKeepBuilds(5)
+		return nil
+	})
+	// The other limits stay
+	if want := replace(t, config, "<numToKeep>10</numToKeep>", "<numToKeep>5</numToKeep>"); got != want {
+		t.Errorf("SetKeepBuilds(5) =\n%s\nwant\n%s", got, want)
+	}
+
+	got = editConfig(t, config, func(c *JobConfig) error {
+		c.SetKeepBuilds(0)
+		return nil
+	})
+	if want := replace(t, config, "<numToKeep>10</numToKeep>", "<numToKeep>-1</numToKeep>"); got != want {
+		t.Errorf("SetKeepBuilds(0) =\n%s\nwant\n%s", got, want)
+	}
+
+	// Without other limits, the build discarder is removed
+	discarder := `
+    <jenkins.model.BuildDiscarderProperty>
+      <strategy class="hudson.tasks.LogRotator">
+        <daysToKeep>30</daysToKeep>
+        <numToKeep>10</numToKeep>
+        <artifactDaysToKeep>-1</artifactDaysToKeep>
+        <artifactNumToKeep>-1</artifactNumToKeep>
+      </strategy>
+    </jenkins.model.BuildDiscarderProperty>`
+	discarder = strings.Replace(discarder, "<daysToKeep>30</daysToKeep>", "<daysToKeep>-1</daysToKeep>", 1)
+	config = replace(t, config, "<daysToKeep>30</daysToKeep>", "<daysToKeep>-1</daysToKeep>")
+	noDiscarder := replace(t, config, discarder, "")
+	got = editConfig(t, config, func(c *JobConfig) error {
+		c.SetKeepBuilds(0)
+		return nil
+	})
+	if got != noDiscarder {
+		t.Errorf("SetKeepBuilds(0) =\n%s\nwant\n%s", got, noDiscarder)
+	}
+
+	// A missing build discarder is added
+	got = editConfig(t, noDiscarder, func(c *JobConfig) error {
+		c.SetKeepBuilds(10)
+		return nil
+	})
+	if want := replace(t, noDiscarder, "\n  </properties>", discarder+"\n  </properties>"); got != want {
+		t.Errorf("SetKeepBuilds(10) =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestJobConfigSetParameter(t *testing.T) {
+	config := readConfig(t)
+	got := editConfig(t, config, func(c *JobConfig) error {
+		return c.SetParameter(Parameter{Type: StringParameter, Name: "VERSION", DefaultValue: "1.0"})
+	})
+	// The parameter keeps its place and description
+	if want := replace(t, config, "<defaultValue>latest</defaultValue>", "<defaultValue>1.0</defaultValue>"); got != want {
+		t.Errorf("SetParameter(VERSION) =\n%s\nwant\n%s", got, want)
+	}
+
+	got = editConfig(t, config, func(c *JobConfig) error {
+		return c.SetParameter(Parameter{Type: BooleanParameter, Name: "DEBUG", Description: "Verbose build", DefaultValue: "true"})
+	})
+	want := replace(t, config, `
+        </hudson.model.ChoiceParameterDefinition>`, `
+        </hudson.model.ChoiceParameterDefinition>
+        <hudson.model.BooleanParameterDefinition>
+          <name>DEBUG</name>
+          <description>Verbose build</description>
+          <defaultValue>true</defaultValue>
+        </hudson.model.BooleanParameterDefinition>`)
+	if got != want {
+		t.Errorf("SetParameter(DEBUG) =\n%s\nwant\n%s", got, want)
+	}
+
+	c, err := ParseJobConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetParameter(Parameter{Type: BooleanParameter, Name: "DEBUG", DefaultValue: "maybe"}); err == nil {
+		t.Error("SetParameter with an invalid boolean default succeeded")
+	}
+}
+
+func TestJobConfigRemoveParameter(t *testing.T) {
+	config := readConfig(t)
+	start := strings.Index(config, "\n    <hudson.model.ParametersDefinitionProperty>")
+	end := strings.Index(config, "</hudson.model.ParametersDefinitionProperty>") + len("</hudson.model.ParametersDefinitionProperty>")
+	want := config[:start] + config[end:]
+	got := editConfig(t, config, func(c *JobConfig) error {
+		for _, name := range []string{"VERSION", "ENV"} {
+			if !c.RemoveParameter(name) {
+				t.Errorf("RemoveParameter(%s) = false", name)
+			}
+		}
+		if c.RemoveParameter("ENV") {
+			t.Error("RemoveParameter of a removed parameter = true")
+		}
+		return nil
+	})
+	// The property of the parameters is removed with the last one
+	if got != want {
+		t.Errorf("RemoveParameter =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestJobConfigTriggers(t *testing.T) {
+	config := readConfig(t)
+	got := editConfig(t, config, func(c *JobConfig) error {
+		c.SetPollSCMTrigger("H/5 * * * *")
+		return nil
+	})
+	want := replace(t, config, `
+        </hudson.triggers.TimerTrigger>`, `
+        </hudson.triggers.TimerTrigger>
+        <hudson.triggers.SCMTrigger>
+          <spec>H/5 * * * *</spec>
+          <ignorePostCommitHooks>false</ignorePostCommitHooks>
+        </hudson.triggers.SCMTrigger>`)
+	if got != want {
+		t.Errorf("SetPollSCMTrigger =\n%s\nwant\n%s", got, want)
+	}
+
+	got = editConfig(t, config, func(c *JobConfig) error {
+		c.SetCronTrigger("")
+		return nil
+	})
+	start := strings.Index(config, "\n    <org.jenkinsci.plugins.workflow.job.properties.PipelineTriggersJobProperty>")
+	end := strings.Index(config, "</org.jenkinsci.plugins.workflow.job.properties.PipelineTriggersJobProperty>") + len("</org.jenkinsci.plugins.workflow.job.properties.PipelineTriggersJobProperty>")
+	if want := config[:start] + config[end:]; got != want {
+		t.Errorf("SetCronTrigger(\"\") =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestJobConfigSettings(t *testing.T) {
+	config := readConfig(t)
+	got := editConfig(t, config, func(c *JobConfig) error {
+		c.SetDescription("Deploys the app")
+		c.SetConcurrentBuilds(true)
+		return c.SetSandbox(false)
+	})
+	want := replace(t, config, `Builds the app &amp; deploys it to &quot;staging&quot;`, "Deploys the app")
+	want = replace(t, want, `
+    <org.jenkinsci.plugins.workflow.job.properties.DisableConcurrentBuildsJobProperty>
+      <abortPrevious>false</abortPrevious>
+    </org.jenkinsci.plugins.workflow.job.properties.DisableConcurrentBuildsJobProperty>`, "")
+	want = replace(t, want, "<sandbox>true</sandbox>", "<sandbox>false</sandbox>")
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	got = editConfig(t, got, func(c *JobConfig) error {
+		c.SetConcurrentBuilds(false)
+		return nil
+	})
+	if !strings.Contains(got, `
+    </org.jenkinsci.plugins.workflow.job.properties.PipelineTriggersJobProperty>
+    <org.jenkinsci.plugins.workflow.job.properties.DisableConcurrentBuildsJobProperty>
+      <abortPrevious>false</abortPrevious>
+    </org.jenkinsci.plugins.workflow.job.properties.DisableConcurrentBuildsJobProperty>
+  </properties>`) {
+		t.Errorf("SetConcurrentBuilds(false) did not add the property:\n%s", got)
+	}
+}
+
+func TestJobConfigFreestyle(t *testing.T) {
+	config := `<?xml version='1.1' encoding='UTF-8'?>
+<project>
+  <description></description>
+  <properties/>
+  <concurrentBuild>false</concurrentBuild>
+  <builders/>
+</project>`
+	c, err := ParseJobConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetConcurrentBuilds(true)
+	c.SetCronTrigger("@daily")
+	if !c.ConcurrentBuilds() || c.CronTrigger() != "@daily" {
+		t.Errorf("ConcurrentBuilds() = %v, CronTrigger() = %q", c.ConcurrentBuilds(), c.CronTrigger())
+	}
+	if err := c.SetSandbox(true); !errors.Is(err, ErrNoPipelineDefinition) {
+		t.Errorf("SetSandbox() = %v, want %v", err, ErrNoPipelineDefinition)
+	}
+	got, err := c.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version='1.1' encoding='UTF-8'?>
+<project>
+  <description></description>
+  <properties/>
+  <concurrentBuild>true</concurrentBuild>
+  <builders/>
+  <triggers>
+    <hudson.triggers.TimerTrigger>
+      <spec>@daily</spec>
+    </hudson.triggers.TimerTrigger>
+  </triggers>
+</project>`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
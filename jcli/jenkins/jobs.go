@@ -0,0 +1,146 @@
+package jenkins
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// PipelineJobConfig is the config.xml of an empty pipeline job, used when
+// there is no job to copy the configuration from
+const PipelineJobConfig = `<?xml version='1.1' encoding='UTF-8'?>
+<flow-definition plugin="workflow-job">
+  <description></description>
+  <keepDependencies>false</keepDependencies>
+  <properties/>
+  <definition class="org.jenkinsci.plugins.workflow.cps.CpsFlowDefinition" plugin="workflow-cps">
+    <script></script>
+    <sandbox>true</sandbox>
+  </definition>
+  <triggers/>
+  <disabled>false</disabled>
+</flow-definition>
+`
+
+// folderConfig is the config.xml of an empty folder
+const folderConfig = `<?xml version='1.1' encoding='UTF-8'?>
+<com.cloudbees.hudson.plugins.folder.Folder plugin="cloudbees-folder"/>
+`
+
+// JobExists reports whether the job or folder exists on the server
+func (j *Jenkins) JobExists(jobName string) (bool, error) {
+	apiUrl := j.JobUrl(jobName) + "/api/json?tree=name"
+	req, err := j.newRequest("GET", apiUrl, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := j.do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("GET %s: %s", apiUrl, resp.Status)
+}
+
+// CreateJob creates the job from its config.xml. The folder of the job has
+// to exist already, see CreateFolders.
+func (j *Jenkins) CreateJob(jobName, config string) error {
+	jobName = strings.Trim(jobName, "/")
+	apiUrl := j.JobUrl(ParentFolder(jobName)) + "/createItem?name=" + url.QueryEscape(path.Base(jobName))
+	req, err := j.newRequest("POST", apiUrl, strings.NewReader(config))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	resp, err := j.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %s: %s", apiUrl, resp.Status)
+	}
+	return nil
+}
+
+// CreateEmptyJob creates an empty pipeline job and its missing folders
+func (j *Jenkins) CreateEmptyJob(jobName string) error {
+	if err := j.CreateFolders(ParentFolder(jobName)); err != nil {
+		return err
+	}
+	return j.CreateJob(jobName, PipelineJobConfig)
+}
+
+// CreateFolders creates the missing folders of a folder path like "a/b/c"
+func (j *Jenkins) CreateFolders(folder string) error {
+	parts := strings.Split(strings.Trim(folder, "/"), "/")
+	for i := range parts {
+		if parts[i] == "" {
+			continue
+		}
+		current := strings.Join(parts[:i+1], "/")
+		exists, err := j.JobExists(current)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if err := j.CreateJob(current, folderConfig); err != nil {
+			return fmt.Errorf("could not create folder %s: %w", current, err)
+		}
+	}
+	return nil
+}
+
+// DeleteJob deletes the job or folder with all its builds
+func (j *Jenkins) DeleteJob(jobName string) error {
+	return j.post(j.JobUrl(jobName) + "/doDelete")
+}
+
+// EnableJob allows the job to build again
+func (j *Jenkins) EnableJob(jobName string) error {
+	return j.post(j.JobUrl(jobName) + "/enable")
+}
+
+// DisableJob stops the job from building until it is enabled again
+func (j *Jenkins) DisableJob(jobName string) error {
+	return j.post(j.JobUrl(jobName) + "/disable")
+}
+
+// RenameJob renames the job inside its folder. The new name is a plain
+// name without folder.
+func (j *Jenkins) RenameJob(jobName, newName string) error {
+	if strings.Contains(newName, "/") {
+		return fmt.Errorf("%s is no valid job name, jobs can only be renamed inside their folder", newName)
+	}
+	return j.post(j.JobUrl(jobName) + "/confirmRename?newName=" + url.QueryEscape(newName))
+}
+
+// CopyJob creates the job newName with the config of jobName. The folder of
+// the new job has to exist already.
+func (j *Jenkins) CopyJob(jobName, newName string) error {
+	newName = strings.Trim(newName, "/")
+	query := url.Values{
+		"name": {path.Base(newName)},
+		"mode": {"copy"},
+		"from": {"/" + strings.Trim(jobName, "/")},
+	}
+	if err := j.post(j.JobUrl(ParentFolder(newName)) + "/createItem?" + query.Encode()); err != nil {
+		return err
+	}
+	// Jenkins does not build copies until their config was saved once
+	config, err := j.GetJobConfig(newName)
+	if err != nil {
+		return err
+	}
+	return j.UpdateJobConfig(newName, config)
+}
@@ -0,0 +1,90 @@
+package jenkins
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// BuildUrl returns the URL of a build of a job. The reference is either a
+// build number or a permalink such as lastBuild or lastFailedBuild.
+// An empty reference points to the last build.
+func (j *Jenkins) BuildUrl(jobName, ref string) string {
+	if ref == "" {
+		ref = "lastBuild"
+	}
+	return j.JobUrl(jobName) + "/" + strings.Trim(ref, "/#") + "/"
+}
+
+// JobNameFromUrl returns the full name of the job of a job or build URL,
+// like "team/app" for .../job/team/job/app/42/
+func JobNameFromUrl(buildUrl string) string {
+	u, err := url.Parse(buildUrl)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	var names []string
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "job" {
+			name, err := url.PathUnescape(parts[i+1])
+			if err != nil {
+				name = parts[i+1]
+			}
+			names = append(names, name)
+			i++
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// ResolveBuildUrl resolves permalinks like lastBuild to the canonical URL
+// of the build, so that the same build is followed even if newer ones start
+func (j *Jenkins) ResolveBuildUrl(jobName, ref string) (string, error) {
+	build, err := j.GetBuild(j.BuildUrl(jobName, ref))
+	if err != nil {
+		return "", err
+	}
+	return build.Url, nil
+}
+
+// GetProgressiveText fetches the console log of a build starting at the byte
+// offset start. It returns the new text, the offset to continue from and
+// whether Jenkins has more data because the build is still running.
+func (j *Jenkins) GetProgressiveText(buildUrl string, start int64) (string, int64, bool, error) {
+	return j.getProgressive(buildUrl, "progressiveText", start)
+}
+
+// GetProgressiveHtml works like GetProgressiveText, but returns the log as
+// HTML with the console annotations and colors rendered by Jenkins
+func (j *Jenkins) GetProgressiveHtml(buildUrl string, start int64) (string, int64, bool, error) {
+	return j.getProgressive(buildUrl, "progressiveHtml", start)
+}
+
+func (j *Jenkins) getProgressive(buildUrl, kind string, start int64) (string, int64, bool, error) {
+	logUrl := strings.TrimSuffix(buildUrl, "/") + "/logText/" + kind + "?start=" + strconv.FormatInt(start, 10)
+	req, err := j.newRequest("GET", logUrl, nil)
+	if err != nil {
+		return "", start, false, err
+	}
+	resp, err := j.do(req)
+	if err != nil {
+		return "", start, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", start, false, fmt.Errorf("GET %s: %s", logUrl, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", start, false, err
+	}
+	next := start + int64(len(body))
+	if size, err := strconv.ParseInt(resp.Header.Get("X-Text-Size"), 10, 64); err == nil {
+		next = size
+	}
+	return string(body), next, resp.Header.Get("X-More-Data") == "true", nil
+}
@@ -0,0 +1,162 @@
+package jenkins
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const nodeTree = "computer[_class,displayName,offline,temporarilyOffline,offlineCauseReason,numExecutors," +
+	"assignedLabels[name],executors[idle,progress,currentExecutable[url,fullDisplayName]]," +
+	"oneOffExecutors[idle,progress,currentExecutable[url,fullDisplayName]]]"
+
+// builtInClass is the class of the node of the controller itself
+const builtInClass = "hudson.model.Hudson$MasterComputer"
+
+type Node struct {
+	Class              string     `json:"_class"`
+	DisplayName        string     `json:"displayName"`
+	Offline            bool       `json:"offline"`
+	TemporarilyOffline bool       `json:"temporarilyOffline"`
+	OfflineCauseReason string     `json:"offlineCauseReason"`
+	NumExecutors       int        `json:"numExecutors"`
+	AssignedLabels     []Label    `json:"assignedLabels"`
+	Executors          []Executor `json:"executors"`
+	// OneOffExecutors run the flyweight tasks of pipelines, which do not
+	// take an executor slot
+	OneOffExecutors []Executor `json:"oneOffExecutors"`
+}
+
+type Label struct {
+	Name string `json:"name"`
+}
+
+type Executor struct {
+	Idle bool `json:"idle"`
+	// Progress is the estimated percentage done, or -1 if unknown
+	Progress          int         `json:"progress"`
+	CurrentExecutable *Executable `json:"currentExecutable"`
+}
+
+// Executable is a build or pipeline step running on an executor
+type Executable struct {
+	Url             string `json:"url"`
+	FullDisplayName string `json:"fullDisplayName"`
+}
+
+// Name returns the name of the node in its URL
+func (n Node) Name() string {
+	if n.Class == builtInClass {
+		return "(built-in)"
+	}
+	return n.DisplayName
+}
+
+// State returns online, offline, or disabled for nodes taken offline on
+// purpose
+func (n Node) State() string {
+	switch {
+	case n.TemporarilyOffline:
+		return "disabled"
+	case n.Offline:
+		return "offline"
+	}
+	return "online"
+}
+
+// Labels returns the labels of the node without its own name, which every
+// node has as a label
+func (n Node) Labels() []string {
+	var labels []string
+	for _, label := range n.AssignedLabels {
+		if label.Name != n.DisplayName && label.Name != "built-in" && label.Name != "master" {
+			labels = append(labels, label.Name)
+		}
+	}
+	return labels
+}
+
+// Busy returns the number of executors running a build
+func (n Node) Busy() int {
+	busy := 0
+	for _, e := range n.Executors {
+		if !e.Idle {
+			busy++
+		}
+	}
+	return busy
+}
+
+// Running returns the builds running on the node
+func (n Node) Running() []Executable {
+	var running []Executable
+	for _, e := range append(n.Executors, n.OneOffExecutors...) {
+		if e.CurrentExecutable != nil {
+			running = append(running, *e.CurrentExecutable)
+		}
+	}
+	return running
+}
+
+// builtInNames are the names of the built-in node besides (built-in): its
+// label and its display name
+var builtInNames = []string{"built-in", "Built-In Node"}
+
+// NodeUrl returns the URL of the node with the name. The built-in node is
+// found by its display name as well.
+func (j *Jenkins) NodeUrl(name string) string {
+	for _, builtIn := range builtInNames {
+		if strings.EqualFold(name, builtIn) {
+			name = "(built-in)"
+		}
+	}
+	return strings.TrimSuffix(j.Address, "/") + "/computer/" + url.PathEscape(name)
+}
+
+// GetNodes returns all nodes of the server
+func (j *Jenkins) GetNodes() ([]Node, error) {
+	var computers struct {
+		Computer []Node `json:"computer"`
+	}
+	apiUrl := strings.TrimSuffix(j.Address, "/") + "/computer/api/json?tree=" + url.QueryEscape(nodeTree)
+	if err := j.getJSON(apiUrl, &computers); err != nil {
+		return nil, err
+	}
+	return computers.Computer, nil
+}
+
+// GetNode returns the node with the name
+func (j *Jenkins) GetNode(name string) (*Node, error) {
+	var node Node
+	tree := strings.TrimSuffix(strings.TrimPrefix(nodeTree, "computer["), "]")
+	if err := j.getJSON(j.NodeUrl(name)+"/api/json?tree="+url.QueryEscape(tree), &node); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// SetNodeOffline takes the node offline with the reason, or updates the
+// reason if it is offline already
+func (j *Jenkins) SetNodeOffline(name, reason string) error {
+	node, err := j.GetNode(name)
+	if err != nil {
+		return err
+	}
+	action := "toggleOffline"
+	if node.TemporarilyOffline {
+		action = "changeOfflineCause"
+	}
+	return j.post(j.NodeUrl(name) + "/" + action + "?offlineMessage=" + url.QueryEscape(reason))
+}
+
+// SetNodeOnline brings a node back online which was taken offline
+func (j *Jenkins) SetNodeOnline(name string) error {
+	node, err := j.GetNode(name)
+	if err != nil {
+		return err
+	}
+	if !node.TemporarilyOffline {
+		return fmt.Errorf("node %s was not taken offline", name)
+	}
+	return j.post(j.NodeUrl(name) + "/toggleOffline")
+}
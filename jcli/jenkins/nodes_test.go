@@ -0,0 +1,18 @@
+package jenkins
+
+import "testing"
+
+func TestNodeUrl(t *testing.T) {
+	j := NewJenkins("http://ci.local/", "alice", "key")
+	for name, want := range map[string]string{
+		"linux-1":       "http://ci.local/computer/linux-1",
+		"(built-in)":    "http://ci.local/computer/%28built-in%29",
+		"Built-In Node": "http://ci.local/computer/%28built-in%29",
+		"built-in":      "http://ci.local/computer/%28built-in%29",
+		"agent 2":       "http://ci.local/computer/agent%202",
+	} {
+		if got := j.NodeUrl(name); got != want {
+			t.Errorf("NodeUrl(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package jenkins
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queueLabelRegexp finds the label or node an item waits for in the reason
+// Jenkins gives, like "Waiting for next available executor on ‘linux’"
+var queueLabelRegexp = regexp.MustCompile(`(?:executor on|label|nodes of label)\s+[‘'"]?([^‘’'"\s]+?)[’'"]?(?:\s|$)|^[‘'"]([^‘’'"]+)[’'"] is offline`)
+
+type QueueItem struct {
+	Id           int    `json:"id"`
+	Why          string `json:"why"`
+	InQueueSince int64  `json:"inQueueSince"`
+	Blocked      bool   `json:"blocked"`
+	Buildable    bool   `json:"buildable"`
+	Stuck        bool   `json:"stuck"`
+	Cancelled    bool   `json:"cancelled"`
+	Url          string `json:"url"`
+	Task         struct {
+		Name string `json:"name"`
+		Url  string `json:"url"`
+	} `json:"task"`
+	// Executable is the build started for the item once it left the queue
+	Executable *BuildLocation `json:"executable"`
+}
+
+type BuildLocation struct {
+	Number int    `json:"number"`
+	Url    string `json:"url"`
+}
+
+// Label returns the label or node the item waits for, or an empty string
+// if it does not wait for an executor
+func (q QueueItem) Label() string {
+	m := queueLabelRegexp.FindStringSubmatch(q.Why)
+	if m == nil {
+		return ""
+	}
+	return m[1] + m[2]
+}
+
+// Waiting returns how long the item has been in the queue
+func (q QueueItem) Waiting() time.Duration {
+	return time.Since(time.UnixMilli(q.InQueueSince)).Truncate(time.Second)
+}
+
+// GetQueue returns all items in the build queue, in the order Jenkins
+// will start them
+func (j *Jenkins) GetQueue() ([]QueueItem, error) {
+	var queue struct {
+		Items []QueueItem `json:"items"`
+	}
+	tree := url.QueryEscape("items[id,why,inQueueSince,blocked,buildable,stuck,url,task[name,url]]")
+	if err := j.getJSON(strings.TrimSuffix(j.Address, "/")+"/queue/api/json?tree="+tree, &queue); err != nil {
+		return nil, err
+	}
+	// The API lists the items which will start soonest last
+	items := queue.Items
+	for i, k := 0, len(items)-1; i < k; i, k = i+1, k-1 {
+		items[i], items[k] = items[k], items[i]
+	}
+	return items, nil
+}
+
+// GetQueueItem fetches the state of a queue item, as returned by QueueBuild
+func (j *Jenkins) GetQueueItem(queueUrl string) (*QueueItem, error) {
+	var item QueueItem
+	if err := j.getJSON(strings.TrimSuffix(queueUrl, "/")+"/api/json", &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// CancelQueueItem removes the item with the id from the queue
+func (j *Jenkins) CancelQueueItem(id int) error {
+	return j.post(strings.TrimSuffix(j.Address, "/") + "/queue/cancelItem?id=" + strconv.Itoa(id))
+}
@@ -0,0 +1,148 @@
+package jenkins
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// replayTextareaRegexp matches the script editors of the replay page
+var replayTextareaRegexp = regexp.MustCompile(`(?s)<textarea[^>]*\sname="_\.([^"]+)"[^>]*>(.*?)</textarea>`)
+
+// ReplayForm holds the scripts of a build as shown on its replay page
+type ReplayForm struct {
+	MainScript string
+	// LoadedScripts are the shared library and loaded scripts, keyed by
+	// their form field. The field is the name of the script, like
+	// "com.example.Utils" or "buildApp" for vars/buildApp.groovy, with dots
+	// replaced by underscores.
+	LoadedScripts map[string]string
+}
+
+// ReplayField returns the form field of a loaded script
+func ReplayField(script string) string {
+	return strings.ReplaceAll(script, ".", "_")
+}
+
+// GetReplayForm fetches the scripts the build ran with
+func (j *Jenkins) GetReplayForm(buildUrl string) (*ReplayForm, error) {
+	replayUrl := strings.TrimSuffix(buildUrl, "/") + "/replay/"
+	req, err := j.newRequest("GET", replayUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", replayUrl, resp.Status)
+	}
+	page, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	form := &ReplayForm{LoadedScripts: map[string]string{}}
+	found := false
+	for _, m := range replayTextareaRegexp.FindAllStringSubmatch(string(page), -1) {
+		// Jelly starts textareas with a newline, which browsers drop
+		script := strings.TrimPrefix(html.UnescapeString(m[2]), "\n")
+		if m[1] == "mainScript" {
+			form.MainScript, found = script, true
+		} else {
+			form.LoadedScripts[m[1]] = script
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%s cannot be replayed", buildUrl)
+	}
+	return form, nil
+}
+
+// Replay runs the build again with the scripts of the form. The form has to
+// contain all loaded scripts of the build.
+func (j *Jenkins) Replay(buildUrl string, form *ReplayForm) error {
+	fields := map[string]string{"mainScript": form.MainScript}
+	for field, script := range form.LoadedScripts {
+		fields[field] = script
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	values := url.Values{"json": {string(data)}, "Submit": {"Run"}}
+	replayUrl := strings.TrimSuffix(buildUrl, "/") + "/replay/run"
+	req, err := j.newRequest("POST", replayUrl, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := j.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("POST %s: %s", replayUrl, resp.Status)
+	}
+	return nil
+}
+
+// NextBuildNumber returns the number the next build of the job will get
+func (j *Jenkins) NextBuildNumber(jobName string) (int, error) {
+	var job struct {
+		NextBuildNumber int `json:"nextBuildNumber"`
+	}
+	if err := j.getJSON(j.JobUrl(jobName)+"/api/json?tree=nextBuildNumber", &job); err != nil {
+		return 0, err
+	}
+	return job.NextBuildNumber, nil
+}
+
+// replayCauseClass is the cause of builds started by replaying another one
+const replayCauseClass = "org.jenkinsci.plugins.workflow.cps.replay.ReplayCause"
+
+// IsReplayOf reports whether the build was started by replaying the build
+// with the number
+func (b Build) IsReplayOf(number int) bool {
+	for _, action := range b.Actions {
+		for _, cause := range action.Causes {
+			if cause.Class == replayCauseClass && cause.OriginalNumber == number {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WaitForReplay waits until the replay of the build with the number original
+// started and returns its URL. Replays do not return a queue item, so the
+// builds from the number from on are checked for the replay cause, skipping
+// builds which other triggers started in the meantime.
+func (j *Jenkins) WaitForReplay(jobName string, original, from int, timeout time.Duration) (string, error) {
+	if original <= 0 {
+		return "", fmt.Errorf("invalid build number %d to wait for the replay of", original)
+	}
+	deadline := time.Now().Add(timeout)
+	for number := from; ; {
+		build, err := j.GetBuild(j.BuildUrl(jobName, fmt.Sprint(number)))
+		if err == nil {
+			if build.IsReplayOf(original) {
+				return build.Url, nil
+			}
+			number++
+			continue
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("the replay of #%d of %s did not start within %s", original, jobName, timeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
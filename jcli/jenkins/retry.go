@@ -0,0 +1,97 @@
+package jenkins
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy sets how requests which only read are retried when the server
+// cannot be reached or is overloaded, like while it restarts
+type RetryPolicy struct {
+	// InitialInterval is the wait before the first retry. It doubles with
+	// every retry up to MaxInterval, with a random jitter of ±50%.
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	// MaxElapsed is how long a request is retried. Zero disables retries.
+	MaxElapsed time.Duration
+}
+
+// DefaultRetryPolicy rides out short outages like restarts of the server or
+// of a load balancer in front of it
+var DefaultRetryPolicy = RetryPolicy{
+	InitialInterval: 500 * time.Millisecond,
+	MaxInterval:     10 * time.Second,
+	MaxElapsed:      time.Minute,
+}
+
+// retryable reports whether a response or error is worth retrying
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the wait the server asked for with the Retry-After
+// header, either in seconds or as a date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+	header := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// backoff returns the wait before the retry with the number, starting at 0
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	interval := p.InitialInterval
+	for i := 0; i < retry && interval < p.MaxInterval; i++ {
+		interval *= 2
+	}
+	interval = min(interval, p.MaxInterval)
+	return time.Duration(float64(interval) * (0.5 + rand.Float64()))
+}
+
+// doWithRetry sends a request which only reads, retrying it with the retry
+// policy of the client
+func (j *Jenkins) doWithRetry(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	for retry := 0; ; retry++ {
+		resp, err := j.client().Do(req)
+		if !retryable(resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+		wait, ok := retryAfter(resp)
+		if !ok {
+			wait = j.Retry.backoff(retry)
+		}
+		if time.Since(start)+wait > j.Retry.MaxElapsed {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if j.OnRetry != nil {
+			j.OnRetry(req, resp, err, wait)
+		}
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package jenkins
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrNoTestReport is returned if a build did not record any test results
+var ErrNoTestReport = errors.New("build has no test report")
+
+type TestReport struct {
+	Duration  float64     `json:"duration"`
+	FailCount int         `json:"failCount"`
+	PassCount int         `json:"passCount"`
+	SkipCount int         `json:"skipCount"`
+	Suites    []TestSuite `json:"suites"`
+}
+
+type TestSuite struct {
+	Name      string     `json:"name"`
+	Duration  float64    `json:"duration"`
+	Timestamp string     `json:"timestamp"`
+	Cases     []TestCase `json:"cases"`
+}
+
+type TestCase struct {
+	ClassName       string  `json:"className"`
+	Name            string  `json:"name"`
+	Status          string  `json:"status"`
+	Duration        float64 `json:"duration"`
+	Age             int     `json:"age"`
+	ErrorDetails    string  `json:"errorDetails"`
+	ErrorStackTrace string  `json:"errorStackTrace"`
+	SkippedMessage  string  `json:"skippedMessage"`
+	Stdout          string  `json:"stdout"`
+	Stderr          string  `json:"stderr"`
+}
+
+// FullName returns the class and test name of the case
+func (c TestCase) FullName() string {
+	return c.ClassName + "." + c.Name
+}
+
+// IsFailed reports whether the test case failed. Jenkins reports failures
+// as FAILED, or REGRESSION if the test passed in the previous build.
+func (c TestCase) IsFailed() bool {
+	return c.Status == "FAILED" || c.Status == "REGRESSION"
+}
+
+// IsSkipped reports whether the test case was skipped
+func (c TestCase) IsSkipped() bool {
+	return c.Status == "SKIPPED"
+}
+
+// GetTestReport returns the JUnit test report of the build at buildUrl
+func (j *Jenkins) GetTestReport(buildUrl string) (*TestReport, error) {
+	reportUrl := strings.TrimSuffix(buildUrl, "/") + "/testReport/api/json"
+	req, err := j.newRequest("GET", reportUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNoTestReport
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", reportUrl, resp.Status)
+	}
+	var report TestReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Failed returns all failing test cases of the report
+func (r *TestReport) Failed() []TestCase {
+	var failed []TestCase
+	for _, suite := range r.Suites {
+		for _, c := range suite.Cases {
+			if c.IsFailed() {
+				failed = append(failed, c)
+			}
+		}
+	}
+	return failed
+}
+
+// Regressions returns the test cases which fail in this report but did not
+// fail in the previous one. Without a previous report, the REGRESSION status
+// computed by Jenkins is used.
+func (r *TestReport) Regressions(previous *TestReport) []TestCase {
+	var regressions []TestCase
+	if previous == nil {
+		for _, c := range r.Failed() {
+			if c.Status == "REGRESSION" {
+				regressions = append(regressions, c)
+			}
+		}
+		return regressions
+	}
+	failedBefore := make(map[string]bool)
+	for _, c := range previous.Failed() {
+		failedBefore[c.FullName()] = true
+	}
+	for _, c := range r.Failed() {
+		if !failedBefore[c.FullName()] {
+			regressions = append(regressions, c)
+		}
+	}
+	return regressions
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// WriteJUnit writes the report as JUnit XML, the format IDEs and most
+// test tooling understand
+func (r *TestReport) WriteJUnit(w io.Writer) error {
+	out := junitTestSuites{
+		Tests:    r.PassCount + r.FailCount + r.SkipCount,
+		Failures: r.FailCount,
+		Skipped:  r.SkipCount,
+		Time:     r.Duration,
+	}
+	for _, suite := range r.Suites {
+		s := junitTestSuite{
+			Name:      suite.Name,
+			Tests:     len(suite.Cases),
+			Time:      suite.Duration,
+			Timestamp: suite.Timestamp,
+		}
+		for _, c := range suite.Cases {
+			tc := junitTestCase{
+				ClassName: c.ClassName,
+				Name:      c.Name,
+				Time:      c.Duration,
+				SystemOut: c.Stdout,
+				SystemErr: c.Stderr,
+			}
+			switch {
+			case c.IsFailed():
+				s.Failures++
+				tc.Failure = &junitFailure{Message: c.ErrorDetails, Text: c.ErrorStackTrace}
+			case c.IsSkipped():
+				s.Skipped++
+				tc.Skipped = &junitSkipped{Message: c.SkippedMessage}
+			}
+			s.Cases = append(s.Cases, tc)
+		}
+		out.Suites = append(out.Suites, s)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
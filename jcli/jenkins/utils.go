@@ -1,7 +1,7 @@
 package jenkins
 
 import (
-	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -13,28 +13,62 @@ import (
 	"github.com/beevik/etree"
 )
 
-// ReplacePipelineScript replaces the pipeline script in the config.xml with the newPipeline
+// ErrNoPipelineScript is returned for jobs which do not keep their pipeline
+// script in the config, like jobs loading the Jenkinsfile from SCM
+var ErrNoPipelineScript = errors.New("job has no inline pipeline script")
+
+// xmlDeclRegexp matches the XML declaration at the start of a document
+var xmlDeclRegexp = regexp.MustCompile(`^\s*<\?xml[^?]*\?>`)
+
+// ReplacePipelineScript replaces the pipeline script in the config.xml with
+// the newPipeline. The rest of the config is left as it is.
 func ReplacePipelineScript(config, newPipeline string) (string, error) {
-	// Save the old xml header
-	oldXMLHeader := config[:38]
-	// Remove xml version 1.1 from config and insert version 1.0
-	config = xml.Header + config[38:]
-	// Parse the XML string
-	doc := etree.NewDocument()
-	if err := doc.ReadFromString(config); err != nil {
+	job, err := ParseJobConfig(config)
+	if err != nil {
 		log.Printf("Error reading XML: %v\n", err)
 		return "", err
 	}
+	if err := job.SetScript(newPipeline); err != nil {
+		return "", err
+	}
+	return job.String()
+}
+
+// ExtractPipelineScript returns the pipeline script of a job's config.xml
+func ExtractPipelineScript(config string) (string, error) {
+	// The XML parser only reads XML 1.0, which Jenkins' configs are
+	// compatible with
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(xmlDeclRegexp.ReplaceAllString(config, "")); err != nil {
+		return "", err
+	}
+	script := doc.FindElement("//definition/script")
+	if script == nil {
+		return "", ErrNoPipelineScript
+	}
+	return script.Text(), nil
+}
 
-	// Find the script element
-	script := doc.FindElement("//script")
-	// Set the text of the script element to the newPipeline
-	script.SetText(newPipeline)
-	updatedConfig, _ := doc.WriteToString()
-	// Add the old xml header back to the updatedConfig
-	updatedConfig = oldXMLHeader + updatedConfig[39:]
-	// log.Println(updatedConfig)
-	return updatedConfig, nil
+// NormalizeConfig formats a config.xml canonically, with sorted attributes
+// and the same indentation everywhere, so that only meaningful differences
+// remain when comparing configs
+func NormalizeConfig(config string) (string, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(xmlDeclRegexp.ReplaceAllString(config, "")); err != nil {
+		return "", err
+	}
+	var sortAttrs func(e *etree.Element)
+	sortAttrs = func(e *etree.Element) {
+		e.SortAttrs()
+		for _, child := range e.ChildElements() {
+			sortAttrs(child)
+		}
+	}
+	if root := doc.Root(); root != nil {
+		sortAttrs(root)
+	}
+	doc.Indent(2)
+	return doc.WriteToString()
 }
 
 // LoadPipelineScriptFromFile loads the pipeline script from a file
@@ -55,13 +89,6 @@ func LoadPipelineScriptFromFile(filename string) (string, error) {
 
 }
 
-// RemovePipelinePart removes the [Pipeline] part from the console output
-func RemovePipelinePart(consoleOutput string) string {
-	regexp := regexp.MustCompile(`(?m)\[Pipeline\].*\n`)
-	res := regexp.ReplaceAllString(consoleOutput, "")
-	return res
-}
-
 func Openbrowser(url string) {
 	var err error
 
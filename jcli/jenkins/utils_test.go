@@ -0,0 +1,50 @@
+package jenkins
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReplacePipelineScript(t *testing.T) {
+	config := readConfig(t)
+	script, err := ExtractPipelineScript(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReplacePipelineScript(config, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != config {
+		t.Errorf("config changed with the same script:\n%s", got)
+	}
+
+	// Only the script changes, escaped like Jenkins does
+	newScript := "pipeline {\n  agent { label 'linux' }\n  stages {\n    stage('Test') {\n      steps {\n        sh \"make test && echo <done>\"\n      }\n    }\n  }\n}"
+	got, err = ReplacePipelineScript(config, newScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := strings.Index(config, "<script>") + len("<script>")
+	end := strings.Index(config, "</script>")
+	want := config[:start] + "pipeline {\n  agent { label &apos;linux&apos; }\n  stages {\n    stage(&apos;Test&apos;) {\n      steps {\n        sh &quot;make test &amp;&amp; echo &lt;done&gt;&quot;\n      }\n    }\n  }\n}" + config[end:]
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if extracted, err := ExtractPipelineScript(got); err != nil || extracted != newScript {
+		t.Errorf("ExtractPipelineScript() = %q, %v, want %q", extracted, err, newScript)
+	}
+}
+
+func TestReplacePipelineScriptWithoutScript(t *testing.T) {
+	config := `<?xml version='1.1' encoding='UTF-8'?>
+<flow-definition>
+  <definition class="org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition">
+    <scriptPath>Jenkinsfile</scriptPath>
+  </definition>
+</flow-definition>`
+	if _, err := ReplacePipelineScript(config, "pipeline {}"); !errors.Is(err, ErrNoPipelineScript) {
+		t.Errorf("ReplacePipelineScript() = %v, want %v", err, ErrNoPipelineScript)
+	}
+}